@@ -1,34 +1,77 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
 	"space-trouble-bookings-api/spacex"
+	"strconv"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type API struct {
-	spacex spacex.Client
-	log    *zap.SugaredLogger
-	db     db.Storage
+	spacex    spacex.Client
+	log       *zap.SugaredLogger
+	db        db.Storage
+	scheduler schedule.Scheduler
+	cfg       Config
+	now       func() time.Time
 }
 
-func NewAPI(spacexClient spacex.Client, storage db.Storage, l *zap.SugaredLogger) *API {
+// Config holds the per-route timeouts and the booking limits. The timeouts limit everything a handler does,
+// including DB queries and SpaceX calls.
+type Config struct {
+	BookingsTimeout          time.Duration
+	BookingTimeout           time.Duration
+	BookFlightTimeout        time.Duration
+	BookingDeleteTimeout     time.Duration
+	BookingRescheduleTimeout time.Duration
+	DestinationsTimeout      time.Duration
+	LaunchpadsTimeout        time.Duration
+	ScheduleTimeout          time.Duration
+	AvailabilityTimeout      time.Duration
+	AdminTimeout             time.Duration
+	WaitlistTimeout          time.Duration
+	// DefaultLaunchCapacity is the number of seats on a flight from a launchpad without its own capacity. 0 means unlimited.
+	DefaultLaunchCapacity int
+	// MaxBodyBytes limits the size of request bodies. 0 means unlimited.
+	MaxBodyBytes int64
+	// IdempotencyKeyTTL is how long idempotency keys are kept. 0 keeps them forever.
+	IdempotencyKeyTTL time.Duration
+}
+
+func NewAPI(spacexClient spacex.Client, storage db.Storage, scheduler schedule.Scheduler, l *zap.SugaredLogger, cfg Config) *API {
 	return &API{
-		spacex: spacexClient,
-		log:    l,
-		db:     storage,
+		spacex:    spacexClient,
+		log:       l,
+		db:        storage,
+		scheduler: scheduler,
+		cfg:       cfg,
+		now:       time.Now,
 	}
 }
 
-type ErrorResponse struct {
-	Message string `json:"message"`
+// requestContext derives the handler context from the request, so a client disconnect cancels the work
+// in progress, and so does the end of the shutdown grace period through the server's BaseContext.
+// A zero timeout leaves only the request's own deadline.
+func requestContext(r *http.Request, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(r.Context())
+	}
+	return context.WithTimeout(r.Context(), timeout)
 }
 
+// ScheduleError is returned when the flight can't be booked. Code tells which of the schedule rules
+// the booking breaks.
 type ScheduleError struct {
+	Code   ErrorCode
 	Reason string
 }
 
@@ -37,21 +80,32 @@ func (e ScheduleError) Error() string {
 }
 
 func (a *API) internalServerError(w http.ResponseWriter) {
-	b, err := json.Marshal(ErrorResponse{Message: "Internal Server Error"})
-	if err != nil {
-		a.log.Error(err)
-	}
-	a.writeResponse(w, b)
+	a.writeProblem(w, newProblem(http.StatusInternalServerError, CodeInternalError, "Internal Server Error"))
 }
 
-func (a *API) writeJSONResponse(w http.ResponseWriter, resp interface{}) {
+// writeJSON writes the response with the status. Every response with a body goes through writeJSON
+// or writeProblem, so the Content-Type is always set and the headers always go before the body.
+func (a *API) writeJSON(w http.ResponseWriter, status int, resp interface{}) {
+	a.write(w, status, jsonContentType, resp)
+}
+
+func (a *API) write(w http.ResponseWriter, status int, contentType string, resp interface{}) {
 	b, err := json.Marshal(resp)
 	if err != nil {
 		a.log.Error(err)
+		a.internalServerError(w)
+		return
 	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
 	a.writeResponse(w, b)
 }
 
+func (a *API) writeNoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (a *API) writeResponse(w http.ResponseWriter, resp []byte) {
 	_, err := w.Write(resp)
 	if err != nil {
@@ -59,7 +113,48 @@ func (a *API) writeResponse(w http.ResponseWriter, resp []byte) {
 	}
 }
 
-func (a *API) writeBadRequest(w http.ResponseWriter, resp ErrorResponse) {
-	w.WriteHeader(http.StatusBadRequest)
-	a.writeJSONResponse(w, resp)
+func (a *API) writeBadRequest(w http.ResponseWriter, code ErrorCode, detail string) {
+	a.writeProblem(w, newProblem(http.StatusBadRequest, code, detail))
+}
+
+func (a *API) writeNotFound(w http.ResponseWriter, code ErrorCode, detail string) {
+	a.writeProblem(w, newProblem(http.StatusNotFound, code, detail))
+}
+
+func (a *API) writeConflict(w http.ResponseWriter, code ErrorCode, detail string) {
+	a.writeProblem(w, newProblem(http.StatusConflict, code, detail))
+}
+
+func (a *API) writeUnprocessableEntity(w http.ResponseWriter, code ErrorCode, detail string) {
+	a.writeProblem(w, newProblem(http.StatusUnprocessableEntity, code, detail))
+}
+
+// writeScheduleError answers 422 when the booking refers to a destination or launchpad that doesn't exist,
+// and 409 when the flight exists but the schedule or the other bookings don't leave room for it.
+func (a *API) writeScheduleError(w http.ResponseWriter, err ScheduleError) {
+	switch err.Code {
+	case CodeDestinationNotFound, CodeLaunchpadNotFound:
+		a.writeUnprocessableEntity(w, err.Code, err.Error())
+	default:
+		a.writeConflict(w, err.Code, err.Error())
+	}
+}
+
+// writeServiceUnavailable tells the client that SpaceX can't be reached and when to retry.
+func (a *API) writeServiceUnavailable(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds())))))
+	a.writeProblem(w, newProblem(http.StatusServiceUnavailable, CodeSpaceXUnavailable, "SpaceX API is unavailable at the moment, try again later"))
+}
+
+// writeServerError answers 503 when the error comes from SpaceX being unavailable, and 500 otherwise.
+func (a *API) writeServerError(w http.ResponseWriter, err error) {
+	var unavailable *spacex.UnavailableError
+	if errors.As(err, &unavailable) {
+		a.log.Warn(err)
+		a.writeServiceUnavailable(w, unavailable.RetryAfter)
+		return
+	}
+
+	a.log.Error(err)
+	a.internalServerError(w)
 }
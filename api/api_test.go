@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_RequestContext(t *testing.T) {
+	bookingBody := `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "jwojeoijwfj"}`
+	testCases := []struct {
+		name          string
+		cfg           Config
+		cancelRequest bool
+		call          func(a *API, w http.ResponseWriter, r *http.Request)
+		method        string
+		body          string
+		expectedErr   error
+	}{
+		{
+			name:          "client disconnect cancels bookings query",
+			cancelRequest: true,
+			call:          (*API).Bookings,
+			method:        "GET",
+			expectedErr:   context.Canceled,
+		},
+		{
+			name:        "route timeout cancels bookings query",
+			cfg:         Config{BookingsTimeout: 10 * time.Millisecond},
+			call:        (*API).Bookings,
+			method:      "GET",
+			expectedErr: context.DeadlineExceeded,
+		},
+		{
+			name:          "client disconnect cancels schedule check",
+			cancelRequest: true,
+			call:          (*API).BookFlight,
+			method:        "POST",
+			body:          bookingBody,
+			expectedErr:   context.Canceled,
+		},
+		{
+			name:        "route timeout cancels schedule check",
+			cfg:         Config{BookFlightTimeout: 10 * time.Millisecond},
+			call:        (*API).BookFlight,
+			method:      "POST",
+			body:        bookingBody,
+			expectedErr: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		dbm := &dbMock{destinations: testDestinations, waitForCancel: true}
+		a := &API{
+			scheduler: schedule.Rotation{},
+			spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "jwojeoijwfj"}}},
+			log:       zap.NewNop().Sugar(),
+			db:        dbm,
+			cfg:       tc.cfg,
+			now:       testNow,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest(tc.method, "/booking", strings.NewReader(tc.body)).WithContext(ctx)
+		if tc.cancelRequest {
+			time.AfterFunc(10*time.Millisecond, cancel)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			tc.call(a, httptest.NewRecorder(), req)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("handler didn't return after the context was done")
+		}
+		cancel()
+
+		if dbm.ctxErr != tc.expectedErr {
+			t.Logf("unexpected storage context error. Got %v, want %v", dbm.ctxErr, tc.expectedErr)
+			t.Fail()
+		}
+	}
+}
+
+func TestAPI_WriteProblem(t *testing.T) {
+	testCases := []struct {
+		name           string
+		write          func(a *API, w http.ResponseWriter)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "schedule error",
+			write: func(a *API, w http.ResponseWriter) {
+				a.writeScheduleError(w, ScheduleError{Code: CodeLaunchpadBusy, Reason: "SpaceX uses the launchpad on that day"})
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody: `{"type":"/problems/launchpad_busy","title":"Launchpad is used by SpaceX","status":409,` +
+				`"detail":"Flight can't be booked: SpaceX uses the launchpad on that day","code":"launchpad_busy"}`,
+		},
+		{
+			name:           "internal error",
+			write:          func(a *API, w http.ResponseWriter) { a.internalServerError(w) },
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody: `{"type":"/problems/internal_error","title":"Internal Server Error","status":500,` +
+				`"detail":"Internal Server Error","code":"internal_error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{log: zap.NewNop().Sugar()}
+		resp := httptest.NewRecorder()
+		tc.write(a, resp)
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
+
+// errReader fails every read, like a body the client stopped sending.
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestAPI_ReadBodyError(t *testing.T) {
+	testCases := []struct {
+		name    string
+		handler func(a *API) http.HandlerFunc
+	}{
+		{name: "book flight", handler: func(a *API) http.HandlerFunc { return a.BookFlight }},
+		{name: "book group", handler: func(a *API) http.HandlerFunc { return a.BookGroup }},
+		{name: "join waitlist", handler: func(a *API) http.HandlerFunc { return a.JoinWaitlist }},
+	}
+	expectedBody := `{"type":"/problems/internal_error","title":"Internal Server Error","status":500,"detail":"Internal Server Error","code":"internal_error"}`
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		// the handler has to stop after the error, so it must not reach the storage
+		a := &API{log: zap.NewNop().Sugar(), now: testNow}
+		resp := httptest.NewRecorder()
+		tc.handler(a)(resp, httptest.NewRequest("POST", "/", errReader{}))
+		if resp.Code != http.StatusInternalServerError {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, http.StatusInternalServerError)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if resp.Body.String() != expectedBody {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), expectedBody)
+			t.Fail()
+		}
+	}
+}
+
+// checkContentType checks the response declares the type of its body: problem+json for errors,
+// JSON for the other bodies and nothing for empty ones.
+func checkContentType(t *testing.T, resp *httptest.ResponseRecorder) {
+	expected := jsonContentType
+	switch {
+	case resp.Code >= http.StatusBadRequest:
+		expected = problemContentType
+	case resp.Body.Len() == 0:
+		expected = ""
+	}
+	if contentType := resp.Header().Get("Content-Type"); contentType != expected {
+		t.Logf("unexpected content type. Got %q, want %q", contentType, expected)
+		t.Fail()
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAvailabilityLimit = 10
+	maxAvailabilityLimit     = 100
+	// maxAvailabilityDays is how far ahead of "from" the search goes before giving up
+	maxAvailabilityDays = 366
+)
+
+type AvailabilityResponse struct {
+	DestinationID int                `json:"destination_id"`
+	Slots         []AvailabilitySlot `json:"slots"`
+}
+
+type AvailabilitySlot struct {
+	Date        string `json:"date"`
+	LaunchpadID string `json:"launchpad_id"`
+	// RemainingSeats is empty when the capacity is unlimited
+	RemainingSeats *int `json:"remaining_seats"`
+}
+
+// Availability finds the next days and launchpads the destination can be booked for.
+// It applies the same rules as the booking itself: the rotation, schedule overrides, SpaceX launches, the destination locked by existing bookings
+// and the seats left on the flight.
+func (a *API) Availability(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AvailabilityTimeout)
+	defer cancel()
+
+	q := r.URL.Query()
+	destinationID, err := strconv.Atoi(q.Get("destination_id"))
+	if err != nil || destinationID < 1 {
+		a.writeBadRequest(w, CodeInvalidRequest, "destination_id should be an integer and >0")
+		return
+	}
+
+	// launches today or earlier can't be booked anymore
+	now := a.now()
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	from := tomorrow
+	if q.Has("from") {
+		from, err = time.Parse(dateFormat, q.Get("from"))
+		if err != nil {
+			a.writeBadRequest(w, CodeInvalidRequest, "from should be in format YYYY-MM-DD")
+			return
+		}
+		if from.Before(tomorrow) {
+			from = tomorrow
+		}
+	}
+
+	limit := defaultAvailabilityLimit
+	if q.Has("limit") {
+		limit, err = strconv.Atoi(q.Get("limit"))
+		if err != nil || limit < 1 || limit > maxAvailabilityLimit {
+			a.writeBadRequest(w, CodeInvalidRequest, fmt.Sprintf("limit should be an integer and be more that 0 and less or equal %d", maxAvailabilityLimit))
+			return
+		}
+	}
+
+	destinations, err := a.getDestinationsMap(ctx, a.db)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	if _, found := destinations[destinationID]; !found {
+		a.writeNotFound(w, CodeDestinationNotFound, "destination doesn't exist")
+		return
+	}
+
+	launchpads, err := a.spacex.GetAllLaunchpads(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	upcomingLaunches, err := a.spacex.GetUpcomingLaunches(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	to := from.AddDate(0, 0, maxAvailabilityDays-1)
+	bookedLaunches, err := a.db.BookedLaunches(ctx, from, to)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	overrides, err := a.db.ScheduleOverrides(ctx, from, to)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	capacities, err := a.db.LaunchpadCapacities(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	launchpadIDs := sortedLaunchpadIDs(launchpads)
+	destinationIDs := sortedDestinationIDs(destinations)
+	busy := a.spacexBusyLaunchpads(upcomingLaunches)
+	firstLaunches := firstLaunchesByDay(bookedLaunches)
+	dayOverrides := overridesByDay(overrides)
+	passengers := passengersByLaunchpadDay(bookedLaunches)
+
+	// the whole range is assigned at once, looking it up day by day would take a query per day with the timetable
+	assigned, err := a.scheduler.AssignRange(ctx, from, to, launchpadIDs, destinationIDs)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	slots := make([]AvailabilitySlot, 0, limit)
+	for i := 0; i < len(assigned) && len(slots) < limit; i++ {
+		date := from.AddDate(0, 0, i).Format(dateFormat)
+		launchpadToDestination := applyOverrides(assigned[i], dayOverrides[date], destinations)
+		closed := closedLaunchpads(dayOverrides[date])
+		firstLaunch := firstLaunches[date]
+
+		for _, launchpadID := range launchpadIDs {
+			key := launchpadDay{date: date, launchpadID: launchpadID}
+			spacexBusy := busy[key]
+			if launchBlocked(launchpadID, destinationID, closed[launchpadID], spacexBusy, firstLaunch) != nil {
+				continue
+			}
+			if !launchScheduled(launchpadID, destinationID, launchpadToDestination[launchpadID], firstLaunch) {
+				continue
+			}
+
+			seats := remainingSeats(a.launchCapacity(capacities, launchpadID), passengers[key])
+			if seats != nil && *seats == 0 {
+				continue
+			}
+
+			slots = append(slots, AvailabilitySlot{Date: date, LaunchpadID: launchpadID, RemainingSeats: seats})
+			if len(slots) == limit {
+				break
+			}
+		}
+	}
+
+	a.writeJSON(w, http.StatusOK, AvailabilityResponse{DestinationID: destinationID, Slots: slots})
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_Availability(t *testing.T) {
+	testCases := []struct {
+		name           string
+		queryParams    url.Values
+		bookings       []db.Booking
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "missing destination_id",
+			queryParams:    url.Values{},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"destination_id should be an integer and \u003e0","code":"invalid_request"}`,
+		},
+		{
+			name:           "destination not found",
+			queryParams:    url.Values{"destination_id": []string{"9"}},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"/problems/destination_not_found","title":"Destination not found","status":404,"detail":"destination doesn't exist","code":"destination_not_found"}`,
+		},
+		{
+			name:           "invalid limit",
+			queryParams:    url.Values{"destination_id": []string{"4"}, "limit": []string{"101"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"limit should be an integer and be more that 0 and less or equal 100","code":"invalid_request"}`,
+		},
+		{
+			// on 2022-10-08 launchpad "b" goes to destination 4 but SpaceX uses it,
+			// on 2022-10-09 launchpad "a" goes to destination 4 but the day is locked to destination 2
+			name:           "skips blocked and locked days",
+			queryParams:    url.Values{"destination_id": []string{"4"}, "from": []string{"2022-10-08"}, "limit": []string{"2"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"destination_id":4,"slots":[{"date":"2022-10-15","launchpad_id":"b","remaining_seats":1},{"date":"2022-10-16","launchpad_id":"a","remaining_seats":2}]}`,
+		},
+		{
+			name:           "search starts tomorrow at the earliest",
+			queryParams:    url.Values{"destination_id": []string{"4"}, "from": []string{"2022-01-01"}, "limit": []string{"1"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"destination_id":4,"slots":[{"date":"2022-09-03","launchpad_id":"b","remaining_seats":1}]}`,
+		},
+		{
+			name:        "skips full flights",
+			queryParams: url.Values{"destination_id": []string{"4"}, "from": []string{"2022-10-15"}, "limit": []string{"1"}},
+			bookings: []db.Booking{
+				{ID: 2, LaunchpadID: "b", DestinationID: 4, LaunchDate: time.Date(2022, 10, 15, 0, 0, 0, 0, time.UTC)},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"destination_id":4,"slots":[{"date":"2022-10-16","launchpad_id":"a","remaining_seats":2}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			scheduler: schedule.Rotation{},
+			spacex: &spacexMock{
+				launchpads:       []spacex.Launchpad{{ID: "a"}, {ID: "b"}},
+				upcomingLaunches: []spacex.Launch{{Launchpad: "b", DateUTC: "2022-10-08T05:40:00.000Z"}},
+			},
+			log: zap.NewNop().Sugar(),
+			db: &dbMock{
+				destinations: testDestinations,
+				bookings: append([]db.Booking{
+					{ID: 1, LaunchpadID: "b", DestinationID: 2, LaunchDate: time.Date(2022, 10, 9, 0, 0, 0, 0, time.UTC)},
+				}, tc.bookings...),
+				capacities: map[string]int{"b": 1},
+			},
+			cfg: Config{DefaultLaunchCapacity: 2},
+			now: testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/availability", nil)
+		req.URL.RawQuery = tc.queryParams.Encode()
+		a.Availability(resp, req)
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
+
+func TestAPI_Availability_TimetableStrategy(t *testing.T) {
+	dbm := &dbMock{
+		destinations: testDestinations,
+		timetable: []db.TimetableEntry{
+			{LaunchDate: time.Date(2022, 9, 5, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 6},
+			{LaunchDate: time.Date(2022, 9, 7, 0, 0, 0, 0, time.UTC), LaunchpadID: "b", DestinationID: 6},
+			{LaunchDate: time.Date(2022, 9, 7, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 3},
+		},
+	}
+	a := &API{
+		scheduler: schedule.NewTimetable(dbm),
+		spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}},
+		log:       zap.NewNop().Sugar(),
+		db:        dbm,
+		now:       testNow,
+	}
+
+	resp := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/availability", nil)
+	req.URL.RawQuery = url.Values{"destination_id": []string{"6"}}.Encode()
+	a.Availability(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Errorf("unexpected status code. Got %d, want %d", resp.Code, http.StatusOK)
+	}
+	checkContentType(t, resp)
+	expectedBody := `{"destination_id":6,"slots":[{"date":"2022-09-05","launchpad_id":"a","remaining_seats":null},{"date":"2022-09-07","launchpad_id":"b","remaining_seats":null}]}`
+	if resp.Body.String() != expectedBody {
+		t.Errorf("unexpected body. Got %s, want %s", resp.Body.String(), expectedBody)
+	}
+
+	// the year searched is loaded at once, not a day at a time
+	if dbm.timetableQueries != 1 {
+		t.Errorf("unexpected number of timetable queries. Got %d, want 1", dbm.timetableQueries)
+	}
+}
@@ -2,12 +2,10 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"sort"
 	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/spacex"
 	"time"
 )
 
@@ -24,149 +22,264 @@ type BookingRequest struct {
 }
 
 func (a *API) BookFlight(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := requestContext(r, a.cfg.BookFlightTimeout)
 	defer cancel()
-	b, err := io.ReadAll(r.Body)
-	if err != nil {
-		a.log.Error(err)
-		a.writeResponse(w, []byte(""))
-		w.WriteHeader(http.StatusInternalServerError)
+	b, ok := a.readBody(w, r)
+	if !ok {
+		return
 	}
 
-	flightBooking := BookingRequest{}
-	err = json.Unmarshal(b, &flightBooking)
-	if err != nil {
-		a.writeBadRequest(w, ErrorResponse{Message: err.Error()})
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+		a.withIdempotencyKey(ctx, w, key, b, a.cfg.BookFlightTimeout, func(w http.ResponseWriter) {
+			a.bookFlight(ctx, w, b)
+		})
 		return
 	}
 
-	launchDate, err := time.Parse("2006-01-02", flightBooking.LaunchDate)
-	if err != nil {
-		a.writeBadRequest(w, ErrorResponse{Message: fmt.Sprintf("Invalid launch date. Should be in format YYYY-MM-DD: %s", err.Error())})
+	a.bookFlight(ctx, w, b)
+}
+
+func (a *API) bookFlight(ctx context.Context, w http.ResponseWriter, b []byte) {
+	flightBooking, requested, ok := a.readBookingRequest(w, b)
+	if !ok {
 		return
 	}
-	if launchDate.Before(time.Now()) {
-		a.writeBadRequest(w, ErrorResponse{Message: "Travels to the past are still in development. Set a launch day in future for now"})
+
+	sx, err := a.getSpaceXData(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
 		return
 	}
 
-	birthday, err := time.Parse("2006-01-02", flightBooking.Birthday)
+	// the schedule check and the insert run under the launch day lock, otherwise concurrent
+	// requests could all pass the check and book different destinations on the same day
+	var booking db.Booking
+	err = a.db.WithLaunchDayLock(ctx, requested.LaunchDate, func(tx db.Storage) error {
+		if err := a.flightSchedulable(ctx, tx, sx, flightBooking, 1); err != nil {
+			return err
+		}
+
+		var err error
+		booking, err = tx.CreateBooking(ctx, requested)
+		return err
+	})
 	if err != nil {
-		a.writeBadRequest(w, ErrorResponse{Message: fmt.Sprintf("Invalid birthday date. Should be in format YYYY-MM-DD: %s", err.Error())})
+		if scheduleErr, ok := err.(ScheduleError); ok {
+			a.writeScheduleError(w, scheduleErr)
+			return
+		}
+
+		a.writeServerError(w, err)
 		return
 	}
-	if birthday.After(time.Now()) {
-		a.writeBadRequest(w, ErrorResponse{Message: "Can't provide flights to someone from the future. Birthday should be in the past."})
-		return
+
+	w.Header().Set("Location", fmt.Sprintf("/booking/%d", booking.ID))
+	a.writeJSON(w, http.StatusCreated, newBooking(booking))
+}
+
+// readBookingRequest parses and validates the booking in the body. When the request is invalid
+// it writes the error response and returns false.
+func (a *API) readBookingRequest(w http.ResponseWriter, b []byte) (BookingRequest, db.Booking, bool) {
+	flightBooking := BookingRequest{}
+	if !a.decodeJSON(w, b, &flightBooking) {
+		return BookingRequest{}, db.Booking{}, false
 	}
 
-	if flightBooking.Gender != "male" && flightBooking.Gender != "female" {
-		a.writeBadRequest(w, ErrorResponse{Message: "Gender should be male or female"})
-		return
+	v := &validator{}
+	booking := a.validateBookingRequest(v, flightBooking)
+	if !v.valid() {
+		a.writeValidationError(w, v)
+		return BookingRequest{}, db.Booking{}, false
 	}
 
-	if len(flightBooking.FirstName) == 0 {
-		a.writeBadRequest(w, ErrorResponse{Message: "field first_name can't be empty"})
-		return
+	return flightBooking, booking, true
+}
+
+// validateBookingRequest checks all the fields of the booking and converts it to the stored form.
+// The problems found are added to v.
+func (a *API) validateBookingRequest(v *validator, flightBooking BookingRequest) db.Booking {
+	launchDate := a.validateFlight(v, flightBooking.LaunchDate, flightBooking.LaunchpadID, flightBooking.DestinationID)
+	booking := a.validatePassenger(v, Passenger{
+		FirstName: flightBooking.FirstName,
+		LastName:  flightBooking.LastName,
+		Gender:    flightBooking.Gender,
+		Birthday:  flightBooking.Birthday,
+	})
+	booking.LaunchDate = launchDate
+	booking.LaunchpadID = flightBooking.LaunchpadID
+	booking.DestinationID = flightBooking.DestinationID
+	return booking
+}
+
+// validateFlight checks the flight fields of the booking and returns the parsed launch date.
+func (a *API) validateFlight(v *validator, launchDateValue string, launchpadID string, destinationID int) time.Time {
+	launchDate, err := time.Parse(dateFormat, launchDateValue)
+	if err != nil {
+		v.add("launch_date", CodeInvalidFormat, fmt.Sprintf("Invalid launch date. Should be in format YYYY-MM-DD: %s", err.Error()))
+	} else if launchDate.Before(a.now()) {
+		v.add("launch_date", CodeInPast, "Travels to the past are still in development. Set a launch day in future for now")
 	}
 
-	if len(flightBooking.LastName) == 0 {
-		a.writeBadRequest(w, ErrorResponse{Message: "field last_name can't be empty"})
-		return
+	if len(launchpadID) == 0 {
+		v.add("launchpad_id", CodeRequired, "field launchpad_id can't be empty")
 	}
 
-	err = a.flightSchedulable(ctx, flightBooking)
+	if destinationID == 0 {
+		v.add("destination_id", CodeRequired, "field destination_id can't be empty")
+	} else if destinationID < 0 {
+		v.add("destination_id", CodeOutOfRange, "destination_id should be an integer and >0")
+	}
+
+	return launchDate
+}
+
+// validatePassenger checks the personal fields of the booking and returns the booking without the flight set.
+func (a *API) validatePassenger(v *validator, passenger Passenger) db.Booking {
+	birthday, err := time.Parse(dateFormat, passenger.Birthday)
 	if err != nil {
-		if _, ok := err.(ScheduleError); ok {
-			a.writeBadRequest(w, ErrorResponse{Message: err.Error()})
-			return
-		}
+		v.add("birthday", CodeInvalidFormat, fmt.Sprintf("Invalid birthday date. Should be in format YYYY-MM-DD: %s", err.Error()))
+	} else if birthday.After(a.now()) {
+		v.add("birthday", CodeInFuture, "Can't provide flights to someone from the future. Birthday should be in the past.")
+	}
 
-		a.log.Error(err)
-		a.internalServerError(w)
-		return
+	if passenger.Gender != "male" && passenger.Gender != "female" {
+		v.add("gender", CodeInvalidValue, "Gender should be male or female")
 	}
 
-	err = a.db.CreateBooking(ctx, db.Booking{
-		FirstName:     flightBooking.FirstName,
-		LastName:      flightBooking.LastName,
-		DestinationID: flightBooking.DestinationID,
-		LaunchpadID:   flightBooking.LaunchpadID,
-		Gender:        flightBooking.Gender,
-		LaunchDate:    launchDate,
-		Birthday:      birthday,
-	})
+	if len(passenger.FirstName) == 0 {
+		v.add("first_name", CodeRequired, "field first_name can't be empty")
+	}
 
-	if err != nil {
-		a.log.Error(err)
-		a.internalServerError(w)
-		return
+	if len(passenger.LastName) == 0 {
+		v.add("last_name", CodeRequired, "field last_name can't be empty")
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	return db.Booking{
+		FirstName: passenger.FirstName,
+		LastName:  passenger.LastName,
+		Gender:    passenger.Gender,
+		Birthday:  birthday,
+	}
 }
 
 func sameDay(t1 time.Time, t2 time.Time) bool {
 	return t1.Day() == t2.Day() && t1.Month() == t2.Month() && t1.Year() == t2.Year()
 }
 
-func (a *API) flightSchedulable(ctx context.Context, flightBooking BookingRequest) error {
+// spacexData is what SpaceX says about its launchpads and launches. Handlers fetch it before taking
+// the launch day lock, so a slow SpaceX doesn't keep the lock, the transaction and its connection held.
+type spacexData struct {
+	launchpads       []spacex.Launchpad
+	upcomingLaunches []spacex.Launch
+}
+
+func (a *API) getSpaceXData(ctx context.Context) (spacexData, error) {
+	launchpads, err := a.spacex.GetAllLaunchpads(ctx)
+	if err != nil {
+		return spacexData{}, err
+	}
+	upcomingLaunches, err := a.spacex.GetUpcomingLaunches(ctx)
+	if err != nil {
+		return spacexData{}, err
+	}
+	return spacexData{launchpads: launchpads, upcomingLaunches: upcomingLaunches}, nil
+}
+
+// flightSchedulable checks the booking of the seats against the schedule. It reads the bookings through storage,
+// so the caller decides whether the check runs inside a transaction.
+func (a *API) flightSchedulable(ctx context.Context, storage db.Storage, sx spacexData, flightBooking BookingRequest, seats int) error {
 	launchDate, err := time.Parse("2006-01-02", flightBooking.LaunchDate)
 	if err != nil {
 		return err
 	}
 
-	destinations, err := a.getDestinationsMap(ctx)
+	destinations, err := a.getDestinationsMap(ctx, storage)
 	if err != nil {
 		return err
 	}
 
 	if _, found := destinations[flightBooking.DestinationID]; !found {
-		return ScheduleError{Reason: fmt.Sprintf("Destination with ID %d not found", flightBooking.DestinationID)}
+		return ScheduleError{Code: CodeDestinationNotFound, Reason: fmt.Sprintf("Destination with ID %d not found", flightBooking.DestinationID)}
 	}
 
-	busy, err := a.launchpadBusy(ctx, launchDate, flightBooking.LaunchpadID)
+	busy := a.launchpadBusy(sx, launchDate, flightBooking.LaunchpadID)
+
+	// cancelled bookings don't lock the day to their destination
+	bookedLaunches, err := storage.BookedLaunches(ctx, launchDate, launchDate)
 	if err != nil {
 		return err
 	}
-	if busy {
-		return ScheduleError{Reason: "SpaceX uses the launchpad on that day"}
+	var firstLaunch *db.BookedLaunch
+	if len(bookedLaunches) > 0 {
+		firstLaunch = &bookedLaunches[0]
 	}
 
-	launchDateBookings, err := a.db.Bookings(ctx, db.BookingsFilter{LaunchDate: launchDate})
+	overrides, err := storage.ScheduleOverrides(ctx, launchDate, launchDate)
 	if err != nil {
 		return err
 	}
-	if len(launchDateBookings) > 0 && launchDateBookings[0].DestinationID != flightBooking.DestinationID && launchDateBookings[0].LaunchpadID != flightBooking.LaunchpadID {
-		return ScheduleError{Reason: fmt.Sprintf("On that day bookings only for destination %d are allowed", launchDateBookings[0].DestinationID)}
+	closed := closedLaunchpads(overrides)
+
+	if err = launchBlocked(flightBooking.LaunchpadID, flightBooking.DestinationID, closed[flightBooking.LaunchpadID], busy, firstLaunch); err != nil {
+		return err
 	}
 
-	launchpadToDestination, err := a.getScheduleForDay(ctx, launchDate, flightBooking, destinations)
+	launchpadToDestination, err := a.getScheduleForDay(ctx, sx, launchDate, flightBooking, destinations)
 	if err != nil {
 		return err
 	}
+	// overrides set by ops take precedence over the scheduling strategy
+	launchpadToDestination = applyOverrides(launchpadToDestination, overrides, destinations)
+
+	scheduledDestinationID := launchpadToDestination[flightBooking.LaunchpadID]
+	if !launchScheduled(flightBooking.LaunchpadID, flightBooking.DestinationID, scheduledDestinationID, firstLaunch) {
+		return ScheduleError{Code: CodeDestinationNotScheduled, Reason: fmt.Sprintf(
+			"No launches available for destination %d(%s) on launchpad %s on %s",
+			flightBooking.DestinationID, destinations[flightBooking.DestinationID],
+			flightBooking.LaunchpadID, flightBooking.LaunchDate,
+		)}
+	}
+	if err = a.flightHasSeats(ctx, storage, launchDate, flightBooking.LaunchpadID, seats); err != nil {
+		return err
+	}
+	if scheduledDestinationID != flightBooking.DestinationID {
+		a.log.Info("According to timetable the flight shouldn't be scheduled, but scheduling anyway since on that day there are booking with that destination already")
+	}
 
-	// if the launchpad's destination matches the client's requested booking destination
-	if launchpadToDestination[flightBooking.LaunchpadID] != flightBooking.DestinationID {
-		// or if there is a different destination on that day (overridden or the schedule shifted because of added/removed launchpads or destinations)
-		// and user requested it
-		if len(launchDateBookings) > 0 && launchDateBookings[0].DestinationID == flightBooking.DestinationID &&
-			launchDateBookings[0].LaunchpadID == flightBooking.LaunchpadID {
-			a.log.Info("According to timetable the flight shouldn't be scheduled, but scheduling anyway since on that day there are booking with that destination already")
-		} else {
-			return ScheduleError{fmt.Sprintf(
-				"No launches available for destination %d(%s) on launchpad %s on %s",
-				flightBooking.DestinationID, destinations[flightBooking.DestinationID],
-				flightBooking.LaunchpadID, flightBooking.LaunchDate,
-			)}
-		}
+	return nil
+}
+
+// flightHasSeats checks the flight from the launchpad on the day has the seats left. Like flightSchedulable,
+// it has to run under the launch day lock to protect from overbooking.
+func (a *API) flightHasSeats(ctx context.Context, storage db.Storage, launchDate time.Time, launchpadID string, seats int) error {
+	capacities, err := storage.LaunchpadCapacities(ctx)
+	if err != nil {
+		return err
+	}
+	capacity := a.launchCapacity(capacities, launchpadID)
+	if capacity == 0 {
+		return nil
+	}
+
+	bookedLaunches, err := storage.BookedLaunches(ctx, launchDate, launchDate)
+	if err != nil {
+		return err
+	}
+	passengers := passengersByLaunchpadDay(bookedLaunches)[launchpadDay{date: launchDate.Format(dateFormat), launchpadID: launchpadID}]
+	if passengers >= capacity {
+		return ScheduleError{Code: CodeFlightFull, Reason: fmt.Sprintf("The flight from launchpad %s on %s is full", launchpadID, launchDate.Format(dateFormat))}
+	}
+	if passengers+seats > capacity {
+		return ScheduleError{Code: CodeFlightFull, Reason: fmt.Sprintf(
+			"The flight from launchpad %s on %s has only %d seats left", launchpadID, launchDate.Format(dateFormat), capacity-passengers,
+		)}
 	}
 
 	return nil
 }
 
-func (a *API) getDestinationsMap(ctx context.Context) (map[int]string, error) {
-	destinations, err := a.db.Destinations(ctx)
+func (a *API) getDestinationsMap(ctx context.Context, storage db.Storage) (map[int]string, error) {
+	destinations, err := storage.Destinations(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -179,56 +292,35 @@ func (a *API) getDestinationsMap(ctx context.Context) (map[int]string, error) {
 	return destinationsMap, nil
 }
 
-func (a *API) launchpadBusy(ctx context.Context, launchDate time.Time, launchpadID string) (bool, error) {
-	upcomingLaunches, err := a.spacex.GetUpcomingLaunches(ctx)
-	if err != nil {
-		return false, err
-	}
-	for _, upcomingLaunch := range upcomingLaunches {
+func (a *API) launchpadBusy(sx spacexData, launchDate time.Time, launchpadID string) bool {
+	for _, upcomingLaunch := range sx.upcomingLaunches {
 		t, err := time.Parse(time.RFC3339, upcomingLaunch.DateUTC)
 		if err != nil {
 			a.log.Errorf("failed to parse upcoming launch time: %s", err.Error())
 		}
 		if sameDay(t, launchDate) && upcomingLaunch.Launchpad == launchpadID {
-			return true, nil
+			return true
 		}
 	}
 
-	return false, nil
+	return false
 }
 
-func (a *API) getScheduleForDay(ctx context.Context, launchDate time.Time, flightBooking BookingRequest, destinations map[int]string) (map[string]int, error) {
-	launchPads, err := a.spacex.GetAllLaunchpads(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	var launchPadIDs []string
+func (a *API) getScheduleForDay(ctx context.Context, sx spacexData, launchDate time.Time, flightBooking BookingRequest, destinations map[int]string) (map[string]int, error) {
 	var requestedLaunchpadFound bool
-	for _, launchPad := range launchPads {
+	for _, launchPad := range sx.launchpads {
 		if launchPad.ID == flightBooking.LaunchpadID {
 			requestedLaunchpadFound = true
 		}
-		launchPadIDs = append(launchPadIDs, launchPad.ID)
 	}
 
 	if !requestedLaunchpadFound {
-		return nil, ScheduleError{Reason: fmt.Sprintf("Requested launchpad with ID %q not found", flightBooking.LaunchpadID)}
+		return nil, ScheduleError{Code: CodeLaunchpadNotFound, Reason: fmt.Sprintf("Requested launchpad with ID %q not found", flightBooking.LaunchpadID)}
 	}
-	sort.Strings(launchPadIDs)
 
-	bookingDay := launchDate.YearDay()
-	pad1Destination := bookingDay%len(destinations) + 1
-
-	launchpadToDestination := make(map[string]int, len(launchPads))
-	for i, id := range launchPadIDs {
-		currentPadDestinationID := pad1Destination + i + 1
-		if pad1Destination+i+1 <= len(destinations) {
-			launchpadToDestination[id] = currentPadDestinationID
-		} else {
-			launchpadToDestination[id] = currentPadDestinationID - len(destinations)
-		}
+	if len(destinations) == 0 {
+		return nil, ScheduleError{Code: CodeNoDestinations, Reason: "No destinations available"}
 	}
 
-	return launchpadToDestination, nil
+	return a.scheduler.Assign(ctx, launchDate, sortedLaunchpadIDs(sx.launchpads), sortedDestinationIDs(destinations))
 }
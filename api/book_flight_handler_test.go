@@ -2,59 +2,86 @@ package api
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
 	"space-trouble-bookings-api/spacex"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"go.uber.org/zap"
 )
 
+var testDestinations = []db.Destination{
+	{ID: 1, Name: "Mars"},
+	{ID: 2, Name: "Moon"},
+	{ID: 3, Name: "Pluto"},
+	{ID: 4, Name: "Asteroid Belt"},
+	{ID: 5, Name: "Europa"},
+	{ID: 6, Name: "Titan"},
+	{ID: 7, Name: "Ganymede"},
+}
+
+// testNow is the clock used by the handlers in tests, so the dates in the test cases don't go stale.
+func testNow() time.Time {
+	return time.Date(2022, 8, 31, 12, 0, 0, 0, time.UTC)
+}
+
 func TestAPI_BookFlight(t *testing.T) {
-	destinations := []db.Destination{
-		{ID: 1, Name: "Mars"},
-		{ID: 2, Name: "Moon"},
-		{ID: 3, Name: "Pluto"},
-		{ID: 4, Name: "Asteroid Belt"},
-		{ID: 5, Name: "Europa"},
-		{ID: 6, Name: "Titan"},
-		{ID: 7, Name: "Ganymede"},
-	}
 	testCases := []struct {
 		name             string
 		body             string
 		launchPads       []spacex.Launchpad
 		upcomingLaunches []spacex.Launch
+		spacexErr        error
 		existingBookings []db.Booking
 		expectedStatus   int
 		expectedBody     string
+		expectedLocation string
+		// expectedRetryAfter is the expected Retry-After header
+		expectedRetryAfter string
 	}{
 		{
 			name:           "invalid json",
 			body:           "invalid json",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   "{\"message\":\"invalid character 'i' looking for beginning of value\"}",
+			expectedBody:   "{\"type\":\"/problems/invalid_request\",\"title\":\"Invalid request\",\"status\":400,\"detail\":\"invalid character 'i' looking for beginning of value\",\"code\":\"invalid_request\"}",
 		},
 		{
 			name:           "invalid launch date",
-			body:           `{"launch_date": "invaliddate"}`,
+			body:           `{"launch_date": "invaliddate", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "a"}`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":"Invalid launch date. Should be in format YYYY-MM-DD: parsing time \"invaliddate\" as \"2006-01-02\": cannot parse \"invaliddate\" as \"2006\""}`,
+			expectedBody:   `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"Invalid launch date. Should be in format YYYY-MM-DD: parsing time \"invaliddate\" as \"2006-01-02\": cannot parse \"invaliddate\" as \"2006\"","code":"validation_failed","errors":[{"field":"launch_date","code":"invalid_format","detail":"Invalid launch date. Should be in format YYYY-MM-DD: parsing time \"invaliddate\" as \"2006-01-02\": cannot parse \"invaliddate\" as \"2006\""}]}`,
 		},
 		{
 			name:           "invalid birthday",
-			body:           `{"launch_date": "2022-10-03", "birthday": "invalid"}`,
+			body:           `{"launch_date": "2022-10-03", "birthday": "invalid", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "a"}`,
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":"Invalid birthday date. Should be in format YYYY-MM-DD: parsing time \"invalid\" as \"2006-01-02\": cannot parse \"invalid\" as \"2006\""}`,
+			expectedBody:   `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"Invalid birthday date. Should be in format YYYY-MM-DD: parsing time \"invalid\" as \"2006-01-02\": cannot parse \"invalid\" as \"2006\"","code":"validation_failed","errors":[{"field":"birthday","code":"invalid_format","detail":"Invalid birthday date. Should be in format YYYY-MM-DD: parsing time \"invalid\" as \"2006-01-02\": cannot parse \"invalid\" as \"2006\""}]}`,
+		},
+		{
+			name:           "all invalid fields are reported",
+			body:           `{"launch_date": "2022-08-01", "birthday": "2030-01-01", "first_name": "fname", "gender": "other", "destination_id": 3, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"Travels to the past are still in development. Set a launch day in future for now; ` +
+				`Can't provide flights to someone from the future. Birthday should be in the past.; Gender should be male or female; field last_name can't be empty","code":"validation_failed",` +
+				`"errors":[{"field":"launch_date","code":"in_past","detail":"Travels to the past are still in development. Set a launch day in future for now"},` +
+				`{"field":"birthday","code":"in_future","detail":"Can't provide flights to someone from the future. Birthday should be in the past."},` +
+				`{"field":"gender","code":"invalid_value","detail":"Gender should be male or female"},` +
+				`{"field":"last_name","code":"required","detail":"field last_name can't be empty"}]}`,
 		},
 		{
 			name:           "destination not found",
 			body:           `{"launch_date": "2022-10-03", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 9, "launchpad_id": "jwojeoijwfj"}`,
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":"Flight can't be booked: Destination with ID 9 not found"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/destination_not_found","title":"Destination not found","status":422,"detail":"Flight can't be booked: Destination with ID 9 not found","code":"destination_not_found"}`,
 		},
 		{
 			name: "lauchpad is busy",
@@ -66,8 +93,8 @@ func TestAPI_BookFlight(t *testing.T) {
 					DateUTC:   "2022-10-03T05:40:00.000Z", //on the same day
 				},
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":"Flight can't be booked: SpaceX uses the launchpad on that day"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/launchpad_busy","title":"Launchpad is used by SpaceX","status":409,"detail":"Flight can't be booked: SpaceX uses the launchpad on that day","code":"launchpad_busy"}`,
 		},
 		{
 			name: "successfully book a ticket",
@@ -84,8 +111,38 @@ func TestAPI_BookFlight(t *testing.T) {
 					ID: "jwojeoijwfj",
 				},
 			},
-			expectedStatus: http.StatusCreated,
-			expectedBody:   ``,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     `{"id":1,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"jwojeoijwfj","destination_id":3,"launch_date":"2022-10-08","status":"confirmed"}`,
+			expectedLocation: "/booking/1",
+		},
+		{
+			name: "cancelled booking doesn't lock the day to its destination",
+			body: `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "jwojeoijwfj"}`,
+			launchPads: []spacex.Launchpad{
+				{
+					ID: "jwojeoijwfj",
+				},
+			},
+			existingBookings: []db.Booking{
+				{
+					ID:            1,
+					LaunchpadID:   "other",
+					DestinationID: 5,
+					LaunchDate:    time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC),
+					Status:        db.BookingStatusCancelled,
+				},
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     `{"id":2,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"jwojeoijwfj","destination_id":3,"launch_date":"2022-10-08","status":"confirmed"}`,
+			expectedLocation: "/booking/2",
+		},
+		{
+			name:               "spacex is unavailable",
+			body:               `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "jwojeoijwfj"}`,
+			spacexErr:          &spacex.UnavailableError{RetryAfter: 1500 * time.Millisecond, Err: spacex.ErrCircuitOpen},
+			expectedStatus:     http.StatusServiceUnavailable,
+			expectedBody:       `{"type":"/problems/spacex_unavailable","title":"SpaceX API is unavailable","status":503,"detail":"SpaceX API is unavailable at the moment, try again later","code":"spacex_unavailable"}`,
+			expectedRetryAfter: "2",
 		},
 		{
 			name: "can't book a ticket for destination on a particular launchpad",
@@ -102,8 +159,8 @@ func TestAPI_BookFlight(t *testing.T) {
 					ID: "jwojeoijwfj",
 				},
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":"Flight can't be booked: No launches available for destination 3(Pluto) on launchpad jwojeoijwfj on 2022-10-03"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_not_scheduled","title":"Destination isn't scheduled","status":409,"detail":"Flight can't be booked: No launches available for destination 3(Pluto) on launchpad jwojeoijwfj on 2022-10-03","code":"destination_not_scheduled"}`,
 		},
 		{
 			name: "launchpad not found",
@@ -120,8 +177,8 @@ func TestAPI_BookFlight(t *testing.T) {
 					ID: "jwojeoijwfj",
 				},
 			},
-			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":"Flight can't be booked: Requested launchpad with ID \"nonexisting\" not found"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/launchpad_not_found","title":"Launchpad not found","status":422,"detail":"Flight can't be booked: Requested launchpad with ID \"nonexisting\" not found","code":"launchpad_not_found"}`,
 		},
 		{
 			name: "schedule even if not according to schedule, but the destination is already scheduled before",
@@ -150,8 +207,9 @@ func TestAPI_BookFlight(t *testing.T) {
 					LaunchDate:    time.Date(2022, 10, 3, 15, 34, 0, 0, time.UTC),
 				},
 			},
-			expectedStatus: http.StatusCreated,
-			expectedBody:   ``,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     `{"id":2,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"jwojeoijwfj","destination_id":3,"launch_date":"2022-10-03","status":"confirmed"}`,
+			expectedLocation: "/booking/2",
 		},
 	}
 
@@ -159,12 +217,346 @@ func TestAPI_BookFlight(t *testing.T) {
 		t.Log(tc.name)
 
 		a := &API{
-			spacex: &spacexMock{launchpads: tc.launchPads, upcomingLaunches: tc.upcomingLaunches},
-			log:    zap.NewNop().Sugar(),
+			scheduler: schedule.Rotation{},
+			spacex:    &spacexMock{launchpads: tc.launchPads, upcomingLaunches: tc.upcomingLaunches, err: tc.spacexErr},
+			log:       zap.NewNop().Sugar(),
 			db: &dbMock{
-				destinations: destinations,
+				destinations: testDestinations,
 				bookings:     tc.existingBookings,
 			},
+			now: testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		a.BookFlight(resp, httptest.NewRequest("POST", "/booking", strings.NewReader(tc.body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+		if tc.expectedLocation != resp.Header().Get("Location") {
+			t.Logf("unexpected Location header. Got %s, want %s", resp.Header().Get("Location"), tc.expectedLocation)
+			t.Fail()
+		}
+		if tc.expectedRetryAfter != resp.Header().Get("Retry-After") {
+			t.Logf("unexpected Retry-After header. Got %s, want %s", resp.Header().Get("Retry-After"), tc.expectedRetryAfter)
+			t.Fail()
+		}
+	}
+}
+
+// dbMock serialises the locked transactions with a mutex, so this only checks that the handler does the check
+// and the insert under the lock. The advisory lock itself is tested against Postgres in the db package.
+func TestAPI_BookFlight_Concurrent(t *testing.T) {
+	// on 2022-10-08 the rotation sends launchpad "a" to destination 3 and launchpad "b" to destination 4,
+	// but only one destination can fly on a day
+	requests := []string{
+		`{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "a"}`,
+		`{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 4, "launchpad_id": "b"}`,
+	}
+	dbm := &dbMock{
+		destinations: testDestinations,
+		delay:        10 * time.Millisecond,
+	}
+	a := &API{
+		scheduler: schedule.Rotation{},
+		spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}},
+		log:       zap.NewNop().Sugar(),
+		db:        dbm,
+		now:       testNow,
+	}
+
+	var wg sync.WaitGroup
+	codes := make([]int, 10)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp := httptest.NewRecorder()
+			a.BookFlight(resp, httptest.NewRequest("POST", "/booking", strings.NewReader(requests[i%len(requests)])))
+			codes[i] = resp.Code
+		}(i)
+	}
+	wg.Wait()
+
+	var created int
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			created++
+		}
+	}
+	if created != len(dbm.bookings) {
+		t.Errorf("unexpected number of bookings. Got %d, want %d", len(dbm.bookings), created)
+	}
+
+	bookedDestinations := map[int]bool{}
+	for _, booking := range dbm.bookings {
+		bookedDestinations[booking.DestinationID] = true
+	}
+	if len(bookedDestinations) != 1 {
+		t.Errorf("expected bookings for exactly one destination, got %v", bookedDestinations)
+	}
+}
+
+func TestAPI_SpaceXOutsideLaunchDayLock(t *testing.T) {
+	// a retried SpaceX request mustn't keep the launch day lock and the transaction held,
+	// so every handler taking the lock asks SpaceX before
+	launchDate := time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name string
+		call func(a *API, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "book flight",
+			call: func(a *API, resp *httptest.ResponseRecorder) {
+				a.BookFlight(resp, httptest.NewRequest("POST", "/booking", strings.NewReader(`{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "a"}`)))
+			},
+		},
+		{
+			name: "book group",
+			call: func(a *API, resp *httptest.ResponseRecorder) {
+				a.BookGroup(resp, httptest.NewRequest("POST", "/booking/group", strings.NewReader(`{"launch_date": "2022-10-08", "destination_id": 3, "launchpad_id": "a", "passengers": [{"birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male"}]}`)))
+			},
+		},
+		{
+			name: "join waitlist",
+			call: func(a *API, resp *httptest.ResponseRecorder) {
+				a.JoinWaitlist(resp, httptest.NewRequest("POST", "/waitlist", strings.NewReader(`{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "a"}`)))
+			},
+		},
+		{
+			name: "reschedule booking",
+			call: func(a *API, resp *httptest.ResponseRecorder) {
+				req := httptest.NewRequest("POST", "/booking/1/reschedule", strings.NewReader(`{"launch_date": "2022-10-09", "launchpad_id": "a"}`))
+				a.RescheduleBooking(resp, withURLParam(req, "id", "1"))
+			},
+		},
+		{
+			name: "delete booking",
+			call: func(a *API, resp *httptest.ResponseRecorder) {
+				a.BookingDelete(resp, withURLParam(httptest.NewRequest("DELETE", "/booking/1", nil), "id", "1"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		dbm := &dbMock{
+			bookings: []db.Booking{
+				{ID: 1, LaunchpadID: "a", DestinationID: 3, LaunchDate: launchDate, Status: db.BookingStatusConfirmed},
+			},
+			waitlist: []db.WaitlistEntry{
+				{ID: 1, LaunchpadID: "a", DestinationID: 3, LaunchDate: launchDate},
+			},
+			destinations: testDestinations,
+		}
+		spacexClient := &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}, db: dbm}
+		a := &API{
+			scheduler: schedule.Rotation{},
+			spacex:    spacexClient,
+			log:       zap.NewNop().Sugar(),
+			db:        dbm,
+			cfg:       Config{DefaultLaunchCapacity: 1},
+			now:       testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		tc.call(a, resp)
+		if resp.Code >= http.StatusInternalServerError {
+			t.Logf("unexpected status code %d: %s", resp.Code, resp.Body.String())
+			t.Fail()
+		}
+		if spacexClient.lockedCalls != 0 {
+			t.Logf("SpaceX called %d times under the launch day lock", spacexClient.lockedCalls)
+			t.Fail()
+		}
+	}
+}
+
+func TestAPI_BookFlight_IdempotencyKey(t *testing.T) {
+	body := `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "jwojeoijwfj"}`
+	created := `{"id":1,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"jwojeoijwfj","destination_id":3,"launch_date":"2022-10-08","status":"confirmed"}`
+	testCases := []struct {
+		name             string
+		key              string
+		body             string
+		expectedStatus   int
+		expectedBody     string
+		expectedLocation string
+	}{
+		{
+			name:             "first request is booked",
+			key:              "key-1",
+			body:             body,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     created,
+			expectedLocation: "/booking/1",
+		},
+		{
+			name:             "repeated request gets the stored response",
+			key:              "key-1",
+			body:             body,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     created,
+			expectedLocation: "/booking/1",
+		},
+		{
+			name:           "reused key with a different body",
+			key:            "key-1",
+			body:           strings.Replace(body, "fname", "other", 1),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/idempotency_key_reused","title":"Idempotency key reused","status":422,"detail":"Idempotency-Key is already used for a different request","code":"idempotency_key_reused"}`,
+		},
+		{
+			name:           "key of a request in progress",
+			key:            "in-progress",
+			body:           body,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/idempotency_key_in_progress","title":"Idempotent request in progress","status":409,"detail":"request with this Idempotency-Key is still in progress","code":"idempotency_key_in_progress"}`,
+		},
+		{
+			name:             "key abandoned in progress is taken over",
+			key:              "abandoned",
+			body:             body,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     strings.Replace(created, `"id":1`, `"id":2`, 1),
+			expectedLocation: "/booking/2",
+		},
+		{
+			name:           "key abandoned by a different request",
+			key:            "abandoned-other",
+			body:           body,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/idempotency_key_reused","title":"Idempotency key reused","status":422,"detail":"Idempotency-Key is already used for a different request","code":"idempotency_key_reused"}`,
+		},
+		{
+			name:             "expired key counts as a new one",
+			key:              "expired",
+			body:             body,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     strings.Replace(created, `"id":1`, `"id":3`, 1),
+			expectedLocation: "/booking/3",
+		},
+	}
+
+	bodyHash := sha256.Sum256([]byte(body))
+	dbm := &dbMock{
+		destinations: testDestinations,
+		idempotencyKeys: map[string]db.IdempotencyKey{
+			"in-progress":     {Key: "in-progress", RequestHash: hex.EncodeToString(bodyHash[:]), CreatedAt: time.Now()},
+			"abandoned":       {Key: "abandoned", RequestHash: hex.EncodeToString(bodyHash[:]), CreatedAt: time.Now().Add(-time.Minute)},
+			"abandoned-other": {Key: "abandoned-other", RequestHash: "other", CreatedAt: time.Now().Add(-time.Minute)},
+			"expired": {
+				Key:         "expired",
+				RequestHash: "other",
+				Response:    &db.IdempotentResponse{Status: http.StatusCreated, Body: []byte(created)},
+				CreatedAt:   time.Now().Add(-48 * time.Hour),
+			},
+		},
+	}
+	a := &API{
+		scheduler: schedule.Rotation{},
+		spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "jwojeoijwfj"}}},
+		log:       zap.NewNop().Sugar(),
+		db:        dbm,
+		cfg:       Config{BookFlightTimeout: 10 * time.Second, IdempotencyKeyTTL: 24 * time.Hour},
+		now:       testNow,
+	}
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/booking", strings.NewReader(tc.body))
+		req.Header.Set(idempotencyKeyHeader, tc.key)
+		a.BookFlight(resp, req)
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+		if tc.expectedLocation != resp.Header().Get("Location") {
+			t.Logf("unexpected Location header. Got %s, want %s", resp.Header().Get("Location"), tc.expectedLocation)
+			t.Fail()
+		}
+	}
+
+	if len(dbm.bookings) != 3 {
+		t.Errorf("unexpected number of bookings. Got %d, want 3", len(dbm.bookings))
+	}
+}
+
+func TestAPI_PurgeIdempotencyKeys(t *testing.T) {
+	dbm := &dbMock{
+		idempotencyKeys: map[string]db.IdempotencyKey{
+			"fresh":   {Key: "fresh", CreatedAt: time.Now()},
+			"expired": {Key: "expired", CreatedAt: time.Now().Add(-48 * time.Hour)},
+		},
+	}
+	a := &API{
+		log: zap.NewNop().Sugar(),
+		db:  dbm,
+		cfg: Config{IdempotencyKeyTTL: 24 * time.Hour},
+	}
+
+	// the keys are purged right away, then it waits for the next round or the end
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.PurgeIdempotencyKeys(ctx)
+
+	if _, ok := dbm.idempotencyKeys["expired"]; ok {
+		t.Error("expired key wasn't deleted")
+	}
+	if _, ok := dbm.idempotencyKeys["fresh"]; !ok {
+		t.Error("fresh key was deleted")
+	}
+}
+
+func TestAPI_BookFlight_TimetableStrategy(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "pinned destination is booked",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 6, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":1,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"a","destination_id":6,"launch_date":"2022-10-08","status":"confirmed"}`,
+		},
+		{
+			// launchpad "b" goes to destination 4 in the rotation, but isn't in the timetable
+			name:           "launchpad without timetable entry doesn't fly",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 4, "launchpad_id": "b"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_not_scheduled","title":"Destination isn't scheduled","status":409,"detail":"Flight can't be booked: No launches available for destination 4(Asteroid Belt) on launchpad b on 2022-10-08","code":"destination_not_scheduled"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		dbm := &dbMock{
+			destinations: testDestinations,
+			timetable: []db.TimetableEntry{
+				{LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 6},
+			},
+		}
+		a := &API{
+			spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}},
+			log:       zap.NewNop().Sugar(),
+			db:        dbm,
+			scheduler: schedule.NewTimetable(dbm),
+			now:       testNow,
 		}
 
 		resp := httptest.NewRecorder()
@@ -173,6 +565,7 @@ func TestAPI_BookFlight(t *testing.T) {
 			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
 			t.Fail()
 		}
+		checkContentType(t, resp)
 		if tc.expectedBody != resp.Body.String() {
 			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
 			t.Fail()
@@ -180,63 +573,453 @@ func TestAPI_BookFlight(t *testing.T) {
 	}
 }
 
+func TestAPI_getScheduleForDay(t *testing.T) {
+	a := &API{
+		scheduler: schedule.Rotation{},
+		log:       zap.NewNop().Sugar(),
+	}
+	sx := spacexData{launchpads: []spacex.Launchpad{{ID: "c"}, {ID: "a"}, {ID: "b"}}}
+	testCases := []struct {
+		name         string
+		destinations map[int]string
+		expected     map[string]int
+	}{
+		{
+			name:         "contiguous destination IDs",
+			destinations: map[int]string{1: "Mars", 2: "Moon", 3: "Pluto", 4: "Europa"},
+			// 2022-10-08 is day 281 of the year
+			expected: map[string]int{"a": 3, "b": 4, "c": 1},
+		},
+		{
+			name:         "deleted destination doesn't take part in the rotation",
+			destinations: map[int]string{1: "Mars", 2: "Moon", 4: "Europa"},
+			expected:     map[string]int{"a": 1, "b": 2, "c": 4},
+		},
+	}
+
+	launchDate := time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		schedule, err := a.getScheduleForDay(context.Background(), sx, launchDate, BookingRequest{LaunchpadID: "a"}, tc.destinations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(schedule) != fmt.Sprint(tc.expected) {
+			t.Logf("unexpected schedule. Got %v, want %v", schedule, tc.expected)
+			t.Fail()
+		}
+	}
+}
+
 type spacexMock struct {
 	launchpads       []spacex.Launchpad
 	upcomingLaunches []spacex.Launch
+	err              error
+	// lockedCalls counts the calls made while db holds a launch day lock
+	db          *dbMock
+	lockedCalls int
 }
 
 func (s *spacexMock) GetUpcomingLaunches(ctx context.Context) ([]spacex.Launch, error) {
-	return s.upcomingLaunches, nil
+	s.countLockedCall()
+	return s.upcomingLaunches, s.err
 }
 
 func (s *spacexMock) GetAllLaunchpads(ctx context.Context) ([]spacex.Launchpad, error) {
-	return s.launchpads, nil
+	s.countLockedCall()
+	return s.launchpads, s.err
+}
+
+func (s *spacexMock) countLockedCall() {
+	if s.db != nil && s.db.locked {
+		s.lockedCalls++
+	}
 }
 
 type dbMock struct {
-	destinations []db.Destination
-	bookings     []db.Booking
+	destinations    []db.Destination
+	bookings        []db.Booking
+	idempotencyKeys map[string]db.IdempotencyKey
+	timetable       []db.TimetableEntry
+	overrides       []db.ScheduleOverride
+	capacities      map[string]int
+	waitlist        []db.WaitlistEntry
+	changes         []db.BookingChange
+	groups          int
+	// delay widens the window between reading the bookings and creating a new one
+	delay time.Duration
+	// waitForCancel makes Bookings block until the context is done and record its error in ctxErr
+	waitForCancel bool
+	ctxErr        error
+	// timetableQueries counts the TimetableEntries calls
+	timetableQueries int
+	// beforeLock runs once before the next locked transaction, like a concurrent request committing first
+	beforeLock func()
+	// locked tells whether a launch day lock is held
+	locked bool
+	mu     sync.Mutex
 }
 
 func (m *dbMock) Bookings(ctx context.Context, filter db.BookingsFilter) ([]db.Booking, error) {
-	return m.bookings, nil
-}
-
-func (m *dbMock) CreateBooking(ctx context.Context, booking db.Booking) error {
-	m.bookings = append(m.bookings, db.Booking{
-		ID:            len(m.bookings) + 1,
-		FirstName:     booking.FirstName,
-		LastName:      booking.LastName,
-		Gender:        booking.Gender,
-		Birthday:      time.Now(),
-		LaunchpadID:   booking.LaunchpadID,
-		DestinationID: booking.DestinationID,
-		LaunchDate:    time.Now(),
+	if m.waitForCancel {
+		<-ctx.Done()
+		m.ctxErr = ctx.Err()
+		return nil, ctx.Err()
+	}
+
+	var bookings []db.Booking
+	for _, booking := range m.bookings {
+		if filter.Status == "" || booking.Status == filter.Status {
+			bookings = append(bookings, booking)
+		}
+	}
+	time.Sleep(m.delay)
+	return bookings, nil
+}
+
+func (m *dbMock) BookedLaunches(ctx context.Context, from, to time.Time) ([]db.BookedLaunch, error) {
+	if m.waitForCancel {
+		<-ctx.Done()
+		m.ctxErr = ctx.Err()
+		return nil, ctx.Err()
+	}
+
+	var launches []db.BookedLaunch
+	index := map[db.BookedLaunch]int{}
+	for _, booking := range m.bookings {
+		// launch_date is a date column, so the time of the day is dropped like in the database
+		launchDate := time.Date(booking.LaunchDate.Year(), booking.LaunchDate.Month(), booking.LaunchDate.Day(), 0, 0, 0, 0, time.UTC)
+		if launchDate.Before(from) || launchDate.After(to) || booking.Status == db.BookingStatusCancelled {
+			continue
+		}
+		key := db.BookedLaunch{LaunchDate: launchDate, LaunchpadID: booking.LaunchpadID, DestinationID: booking.DestinationID}
+		if i, ok := index[key]; ok {
+			launches[i].Passengers++
+			continue
+		}
+		index[key] = len(launches)
+		key.Passengers = 1
+		launches = append(launches, key)
+	}
+	sort.SliceStable(launches, func(i, j int) bool {
+		return launches[i].LaunchDate.Before(launches[j].LaunchDate)
 	})
-	return nil
+	time.Sleep(m.delay)
+
+	return launches, nil
+}
+
+func (m *dbMock) Booking(ctx context.Context, id int) (db.Booking, error) {
+	for _, booking := range m.bookings {
+		if booking.ID == id {
+			return booking, nil
+		}
+	}
+
+	return db.Booking{}, db.ErrNotFound
+}
+
+func (m *dbMock) CreateBooking(ctx context.Context, booking db.Booking) (db.Booking, error) {
+	booking.ID = 1
+	for _, existing := range m.bookings {
+		if existing.ID >= booking.ID {
+			booking.ID = existing.ID + 1
+		}
+	}
+	booking.Status = db.BookingStatusConfirmed
+	m.bookings = append(m.bookings, booking)
+	return booking, nil
+}
+
+func (m *dbMock) CreateBookingGroup(ctx context.Context) (int, error) {
+	m.groups++
+	return m.groups, nil
 }
 
 func (m *dbMock) Destinations(ctx context.Context) ([]db.Destination, error) {
 	return m.destinations, nil
 }
 
-func (m *dbMock) BookingExists(ctx context.Context, id int) (bool, error) {
+func (m *dbMock) CreateDestination(ctx context.Context, name string) (db.Destination, error) {
+	for _, destination := range m.destinations {
+		if destination.Name == name {
+			return db.Destination{}, db.ErrAlreadyExists
+		}
+	}
+
+	destination := db.Destination{ID: len(m.destinations) + 1, Name: name}
+	m.destinations = append(m.destinations, destination)
+	return destination, nil
+}
+
+func (m *dbMock) UpdateDestination(ctx context.Context, id int, name string) (db.Destination, error) {
+	for _, destination := range m.destinations {
+		if destination.Name == name && destination.ID != id {
+			return db.Destination{}, db.ErrAlreadyExists
+		}
+	}
+	for i, destination := range m.destinations {
+		if destination.ID == id {
+			m.destinations[i].Name = name
+			return m.destinations[i], nil
+		}
+	}
+
+	return db.Destination{}, db.ErrNotFound
+}
+
+func (m *dbMock) DeleteDestination(ctx context.Context, id int, from time.Time) error {
 	for _, booking := range m.bookings {
-		if booking.ID == id {
-			return true, nil
+		if booking.DestinationID == id && !booking.LaunchDate.Before(from) {
+			return db.ErrInUse
+		}
+	}
+	for _, entry := range m.timetable {
+		if entry.DestinationID == id && !entry.LaunchDate.Before(from) {
+			return db.ErrInUse
+		}
+	}
+	for _, override := range m.overrides {
+		if override.DestinationID != nil && *override.DestinationID == id && !override.LaunchDate.Before(from) {
+			return db.ErrInUse
+		}
+	}
+	for _, entry := range m.waitlist {
+		if entry.DestinationID == id && !entry.LaunchDate.Before(from) && entry.PromotedBookingID == nil {
+			return db.ErrInUse
+		}
+	}
+	for i, destination := range m.destinations {
+		if destination.ID == id {
+			m.destinations = append(m.destinations[:i], m.destinations[i+1:]...)
+			return nil
 		}
 	}
 
-	return false, nil
+	return db.ErrNotFound
 }
 
-func (m *dbMock) BookingDelete(ctx context.Context, id int) error {
+func (m *dbMock) CancelBooking(ctx context.Context, id int, reason string) (db.Booking, error) {
 	for i, booking := range m.bookings {
-		if booking.ID == i {
-			m.bookings = append(m.bookings[:i], m.bookings[i+1:]...)
+		if booking.ID == id && booking.Status == db.BookingStatusConfirmed {
+			cancelledAt := testNow()
+			m.bookings[i].Status = db.BookingStatusCancelled
+			m.bookings[i].CancelledAt = &cancelledAt
+			m.bookings[i].CancellationReason = reason
+			return m.bookings[i], nil
+		}
+	}
+
+	return db.Booking{}, db.ErrNotFound
+}
+
+func (m *dbMock) RescheduleBooking(ctx context.Context, id int, flight db.Flight) (db.Booking, error) {
+	for i, booking := range m.bookings {
+		if booking.ID == id && booking.Status == db.BookingStatusConfirmed {
+			m.bookings[i].LaunchDate = flight.LaunchDate
+			m.bookings[i].LaunchpadID = flight.LaunchpadID
+			m.bookings[i].DestinationID = flight.DestinationID
+			return m.bookings[i], nil
+		}
+	}
+
+	return db.Booking{}, db.ErrNotFound
+}
+
+func (m *dbMock) CreateBookingChange(ctx context.Context, change db.BookingChange) (db.BookingChange, error) {
+	change.ID = len(m.changes) + 1
+	change.ChangedAt = testNow()
+	m.changes = append(m.changes, change)
+	return change, nil
+}
+
+func (m *dbMock) BookingChanges(ctx context.Context, bookingID int) ([]db.BookingChange, error) {
+	var changes []db.BookingChange
+	for _, change := range m.changes {
+		if change.BookingID == bookingID {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func (m *dbMock) CreateWaitlistEntry(ctx context.Context, entry db.WaitlistEntry) (db.WaitlistEntry, error) {
+	entry.ID = len(m.waitlist) + 1
+	entry.CreatedAt = testNow()
+	m.waitlist = append(m.waitlist, entry)
+	return entry, nil
+}
+
+func (m *dbMock) OldestWaitlistEntry(ctx context.Context, launch db.BookedLaunch) (db.WaitlistEntry, error) {
+	for _, entry := range m.waitlist {
+		if entry.PromotedBookingID == nil && entry.LaunchDate.Equal(launch.LaunchDate) &&
+			entry.LaunchpadID == launch.LaunchpadID && entry.DestinationID == launch.DestinationID {
+			return entry, nil
+		}
+	}
+	return db.WaitlistEntry{}, db.ErrNotFound
+}
+
+func (m *dbMock) PromoteWaitlistEntry(ctx context.Context, id, bookingID int) error {
+	for i, entry := range m.waitlist {
+		if entry.ID == id && entry.PromotedBookingID == nil {
+			promotedAt := testNow()
+			m.waitlist[i].PromotedBookingID = &bookingID
+			m.waitlist[i].PromotedAt = &promotedAt
+			return nil
+		}
+	}
+	return db.ErrNotFound
+}
+
+func (m *dbMock) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, abandonAfter, expireAfter time.Duration) (db.IdempotencyKey, bool, error) {
+	if stored, ok := m.idempotencyKeys[key]; ok {
+		age := time.Since(stored.CreatedAt)
+		expired := expireAfter > 0 && age > expireAfter
+		abandoned := abandonAfter > 0 && stored.Response == nil && stored.RequestHash == requestHash && age > abandonAfter
+		if !expired && !abandoned {
+			return stored, false, nil
+		}
+	}
+
+	if m.idempotencyKeys == nil {
+		m.idempotencyKeys = map[string]db.IdempotencyKey{}
+	}
+	m.idempotencyKeys[key] = db.IdempotencyKey{Key: key, RequestHash: requestHash, CreatedAt: time.Now()}
+	return m.idempotencyKeys[key], true, nil
+}
+
+func (m *dbMock) SaveIdempotentResponse(ctx context.Context, key string, resp db.IdempotentResponse) error {
+	stored := m.idempotencyKeys[key]
+	stored.Response = &resp
+	m.idempotencyKeys[key] = stored
+	return nil
+}
+
+func (m *dbMock) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	delete(m.idempotencyKeys, key)
+	return nil
+}
+
+func (m *dbMock) DeleteExpiredIdempotencyKeys(ctx context.Context, expireAfter time.Duration) (int64, error) {
+	var deleted int64
+	for key, stored := range m.idempotencyKeys {
+		if time.Since(stored.CreatedAt) > expireAfter {
+			delete(m.idempotencyKeys, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *dbMock) TimetableEntries(ctx context.Context, from, to time.Time) ([]db.TimetableEntry, error) {
+	m.timetableQueries++
+	var entries []db.TimetableEntry
+	for _, entry := range m.timetable {
+		if !entry.LaunchDate.Before(from) && !entry.LaunchDate.After(to) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (m *dbMock) SetTimetableEntry(ctx context.Context, entry db.TimetableEntry) error {
+	for i, existing := range m.timetable {
+		if existing.LaunchDate.Equal(entry.LaunchDate) && existing.LaunchpadID == entry.LaunchpadID {
+			m.timetable[i] = entry
+			return nil
+		}
+	}
+	m.timetable = append(m.timetable, entry)
+	return nil
+}
+
+func (m *dbMock) DeleteTimetableEntry(ctx context.Context, launchDate time.Time, launchpadID string) error {
+	for i, existing := range m.timetable {
+		if existing.LaunchDate.Equal(launchDate) && existing.LaunchpadID == launchpadID {
+			m.timetable = append(m.timetable[:i], m.timetable[i+1:]...)
+			return nil
+		}
+	}
+	return db.ErrNotFound
+}
+
+func (m *dbMock) ScheduleOverrides(ctx context.Context, from, to time.Time) ([]db.ScheduleOverride, error) {
+	var overrides []db.ScheduleOverride
+	for _, override := range m.overrides {
+		if !override.LaunchDate.Before(from) && !override.LaunchDate.After(to) {
+			overrides = append(overrides, override)
+		}
+	}
+	return overrides, nil
+}
+
+func (m *dbMock) SetScheduleOverride(ctx context.Context, override db.ScheduleOverride) error {
+	for i, existing := range m.overrides {
+		if existing.LaunchDate.Equal(override.LaunchDate) && existing.LaunchpadID == override.LaunchpadID {
+			m.overrides[i] = override
+			return nil
+		}
+	}
+	m.overrides = append(m.overrides, override)
+	return nil
+}
+
+func (m *dbMock) DeleteScheduleOverride(ctx context.Context, launchDate time.Time, launchpadID string) error {
+	for i, existing := range m.overrides {
+		if existing.LaunchDate.Equal(launchDate) && existing.LaunchpadID == launchpadID {
+			m.overrides = append(m.overrides[:i], m.overrides[i+1:]...)
 			return nil
 		}
 	}
+	return db.ErrNotFound
+}
+
+func (m *dbMock) LaunchpadCapacities(ctx context.Context) (map[string]int, error) {
+	capacities := make(map[string]int, len(m.capacities))
+	for launchpadID, seats := range m.capacities {
+		capacities[launchpadID] = seats
+	}
+	return capacities, nil
+}
+
+func (m *dbMock) SetLaunchpadCapacity(ctx context.Context, launchpadID string, seats int) error {
+	if m.capacities == nil {
+		m.capacities = make(map[string]int)
+	}
+	m.capacities[launchpadID] = seats
+	return nil
+}
 
+func (m *dbMock) DeleteLaunchpadCapacity(ctx context.Context, launchpadID string) error {
+	if _, ok := m.capacities[launchpadID]; !ok {
+		return db.ErrNotFound
+	}
+	delete(m.capacities, launchpadID)
 	return nil
 }
+
+func (m *dbMock) WithLaunchDayLock(ctx context.Context, launchDate time.Time, fn func(tx db.Storage) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.beforeLock != nil {
+		m.beforeLock()
+		m.beforeLock = nil
+	}
+	m.locked = true
+	defer func() { m.locked = false }()
+	return fn(m)
+}
+
+func (m *dbMock) WithLaunchDaysLock(ctx context.Context, launchDates []time.Time, fn func(tx db.Storage) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.beforeLock != nil {
+		m.beforeLock()
+		m.beforeLock = nil
+	}
+	m.locked = true
+	defer func() { m.locked = false }()
+	return fn(m)
+}
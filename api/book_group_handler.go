@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"space-trouble-bookings-api/db"
+)
+
+// GroupBookingRequest books several passengers together on one flight.
+type GroupBookingRequest struct {
+	LaunchpadID   string      `json:"launchpad_id"`
+	DestinationID int         `json:"destination_id"`
+	LaunchDate    string      `json:"launch_date"`
+	Passengers    []Passenger `json:"passengers"`
+}
+
+type Passenger struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Gender    string `json:"gender"`
+	Birthday  string `json:"birthday"`
+}
+
+type GroupBookingResponse struct {
+	GroupID  int       `json:"group_id"`
+	Bookings []Booking `json:"bookings"`
+}
+
+// BookGroup books all the passengers of the group on the flight, or none of them.
+func (a *API) BookGroup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.BookFlightTimeout)
+	defer cancel()
+	b, ok := a.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+		a.withIdempotencyKey(ctx, w, key, b, a.cfg.BookFlightTimeout, func(w http.ResponseWriter) {
+			a.bookGroup(ctx, w, b)
+		})
+		return
+	}
+
+	a.bookGroup(ctx, w, b)
+}
+
+func (a *API) bookGroup(ctx context.Context, w http.ResponseWriter, b []byte) {
+	groupBooking := GroupBookingRequest{}
+	if !a.decodeJSON(w, b, &groupBooking) {
+		return
+	}
+
+	// every passenger goes through the same validation as a single booking
+	v := &validator{}
+	launchDate := a.validateFlight(v, groupBooking.LaunchDate, groupBooking.LaunchpadID, groupBooking.DestinationID)
+	if len(groupBooking.Passengers) == 0 {
+		v.add("passengers", CodeRequired, "field passengers can't be empty")
+	}
+	requested := make([]db.Booking, 0, len(groupBooking.Passengers))
+	for i, passenger := range groupBooking.Passengers {
+		pv := &validator{}
+		booking := a.validatePassenger(pv, passenger)
+		v.merge(fmt.Sprintf("passengers[%d]", i), pv)
+
+		booking.LaunchDate = launchDate
+		booking.LaunchpadID = groupBooking.LaunchpadID
+		booking.DestinationID = groupBooking.DestinationID
+		requested = append(requested, booking)
+	}
+	if !v.valid() {
+		a.writeValidationError(w, v)
+		return
+	}
+
+	flightBooking := groupBooking.bookingRequest(groupBooking.Passengers[0])
+	sx, err := a.getSpaceXData(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	// the whole group is booked in one transaction under the launch day lock,
+	// so either every passenger gets a seat or nobody does
+	var groupID int
+	var bookings []db.Booking
+	err = a.db.WithLaunchDayLock(ctx, launchDate, func(tx db.Storage) error {
+		if err := a.flightSchedulable(ctx, tx, sx, flightBooking, len(requested)); err != nil {
+			return err
+		}
+
+		var err error
+		groupID, err = tx.CreateBookingGroup(ctx)
+		if err != nil {
+			return err
+		}
+		for _, booking := range requested {
+			booking.GroupID = &groupID
+			booking, err = tx.CreateBooking(ctx, booking)
+			if err != nil {
+				return err
+			}
+			bookings = append(bookings, booking)
+		}
+		return nil
+	})
+	if err != nil {
+		if scheduleErr, ok := err.(ScheduleError); ok {
+			a.writeScheduleError(w, scheduleErr)
+			return
+		}
+
+		a.writeServerError(w, err)
+		return
+	}
+
+	respBookings := make([]Booking, 0, len(bookings))
+	for _, booking := range bookings {
+		respBookings = append(respBookings, newBooking(booking))
+	}
+
+	a.writeJSON(w, http.StatusCreated, GroupBookingResponse{GroupID: groupID, Bookings: respBookings})
+}
+
+// bookingRequest returns the booking of the passenger on the group's flight.
+func (g GroupBookingRequest) bookingRequest(passenger Passenger) BookingRequest {
+	return BookingRequest{
+		FirstName:     passenger.FirstName,
+		LastName:      passenger.LastName,
+		Gender:        passenger.Gender,
+		Birthday:      passenger.Birthday,
+		LaunchpadID:   g.LaunchpadID,
+		DestinationID: g.DestinationID,
+		LaunchDate:    g.LaunchDate,
+	}
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_BookGroup(t *testing.T) {
+	// on 2022-10-08 the rotation sends launchpad "a" to destination 3
+	testCases := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedBody     string
+		expectedBookings int
+	}{
+		{
+			name:             "invalid json",
+			body:             `{`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"unexpected EOF","code":"invalid_request"}`,
+			expectedBookings: 1,
+		},
+		{
+			name:             "no passengers",
+			body:             `{"launchpad_id": "a", "destination_id": 3, "launch_date": "2022-10-08", "passengers": []}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"field passengers can't be empty","code":"validation_failed","errors":[{"field":"passengers","code":"required","detail":"field passengers can't be empty"}]}`,
+			expectedBookings: 1,
+		},
+		{
+			name: "invalid passenger",
+			body: `{"launchpad_id": "a", "destination_id": 3, "launch_date": "2022-10-08", "passengers": [` +
+				`{"first_name": "Ann", "last_name": "Lee", "gender": "female", "birthday": "1990-01-02"},` +
+				`{"first_name": "Tom", "last_name": "Lee", "gender": "other", "birthday": "2015-05-06"}]}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"passengers[1]: Gender should be male or female","code":"validation_failed","errors":[{"field":"passengers[1].gender","code":"invalid_value","detail":"passengers[1]: Gender should be male or female"}]}`,
+			expectedBookings: 1,
+		},
+		{
+			name: "destination isn't scheduled",
+			body: `{"launchpad_id": "a", "destination_id": 4, "launch_date": "2022-10-08", "passengers": [` +
+				`{"first_name": "Ann", "last_name": "Lee", "gender": "female", "birthday": "1990-01-02"}]}`,
+			expectedStatus:   http.StatusConflict,
+			expectedBody:     `{"type":"/problems/destination_not_scheduled","title":"Destination isn't scheduled","status":409,"detail":"Flight can't be booked: No launches available for destination 4(Asteroid Belt) on launchpad a on 2022-10-08","code":"destination_not_scheduled"}`,
+			expectedBookings: 1,
+		},
+		{
+			name: "not enough seats for the whole group",
+			body: `{"launchpad_id": "a", "destination_id": 3, "launch_date": "2022-10-08", "passengers": [` +
+				`{"first_name": "Ann", "last_name": "Lee", "gender": "female", "birthday": "1990-01-02"},` +
+				`{"first_name": "Tom", "last_name": "Lee", "gender": "male", "birthday": "2015-05-06"},` +
+				`{"first_name": "Joe", "last_name": "Lee", "gender": "male", "birthday": "1988-03-04"}]}`,
+			expectedStatus:   http.StatusConflict,
+			expectedBody:     `{"type":"/problems/flight_full","title":"Flight is full","status":409,"detail":"Flight can't be booked: The flight from launchpad a on 2022-10-08 has only 2 seats left","code":"flight_full"}`,
+			expectedBookings: 1,
+		},
+		{
+			name: "success",
+			body: `{"launchpad_id": "a", "destination_id": 3, "launch_date": "2022-10-08", "passengers": [` +
+				`{"first_name": "Ann", "last_name": "Lee", "gender": "female", "birthday": "1990-01-02"},` +
+				`{"first_name": "Tom", "last_name": "Lee", "gender": "male", "birthday": "2015-05-06"}]}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody: `{"group_id":1,"bookings":[` +
+				`{"id":2,"first_name":"Ann","last_name":"Lee","gender":"female","birthday":"1990-01-02","launchpad_id":"a","destination_id":3,"launch_date":"2022-10-08","status":"confirmed","group_id":1},` +
+				`{"id":3,"first_name":"Tom","last_name":"Lee","gender":"male","birthday":"2015-05-06","launchpad_id":"a","destination_id":3,"launch_date":"2022-10-08","status":"confirmed","group_id":1}]}`,
+			expectedBookings: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		dbm := &dbMock{
+			destinations: testDestinations,
+			bookings: []db.Booking{
+				{ID: 1, LaunchpadID: "a", DestinationID: 3, LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), Status: db.BookingStatusConfirmed},
+			},
+			capacities: map[string]int{"a": 3},
+		}
+		a := &API{
+			spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}},
+			log:       zap.NewNop().Sugar(),
+			db:        dbm,
+			scheduler: schedule.Rotation{},
+			now:       testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		a.BookGroup(resp, httptest.NewRequest("POST", "/booking/group", strings.NewReader(tc.body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+		if len(dbm.bookings) != tc.expectedBookings {
+			t.Logf("unexpected number of bookings. Got %d, want %d", len(dbm.bookings), tc.expectedBookings)
+			t.Fail()
+		}
+	}
+}
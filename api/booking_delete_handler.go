@@ -2,40 +2,155 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/spacex"
 	"strconv"
-	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// BookingDelete cancels the booking. The row is kept with its status, cancellation time and the optional
+// reason from the query.
 func (a *API) BookingDelete(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	ctx, cancel := requestContext(r, a.cfg.BookingDeleteTimeout)
 	defer cancel()
 	idStr := chi.URLParam(r, "id")
 
 	id, err := strconv.Atoi(idStr)
 	if err != nil || id < 1 {
-		a.writeBadRequest(w, ErrorResponse{Message: "booking id should be an integer and >0"})
+		a.writeBadRequest(w, CodeInvalidRequest, "booking id should be an integer and >0")
 		return
 	}
 
-	exists, err := a.db.BookingExists(ctx, id)
+	booking, err := a.db.Booking(ctx, id)
 	if err != nil {
-		a.log.Error(err)
-		a.internalServerError(w)
+		if err == db.ErrNotFound {
+			a.writeNotFound(w, CodeBookingNotFound, "booking doesn't exist")
+			return
+		}
+		a.writeServerError(w, err)
 		return
 	}
-	if !exists {
-		a.writeBadRequest(w, ErrorResponse{Message: "booking doesn't exist"})
+	if booking.Status != db.BookingStatusConfirmed {
+		a.writeConflict(w, CodeBookingNotConfirmed, fmt.Sprintf("booking is %s already", booking.Status))
 		return
 	}
+	// a launched flight's booking is left for the flown or no_show transition
+	if booking.LaunchDate.Before(a.now()) {
+		a.writeConflict(w, CodeLaunchInPast, "the flight has launched already, the booking can't be cancelled anymore")
+		return
+	}
+
+	// the seat is freed anyway, SpaceX being down mustn't fail the cancellation, only the waitlist waits then
+	var sx *spacexData
+	if data, err := a.getSpaceXData(ctx); err == nil {
+		sx = &data
+	} else if errors.As(err, new(*spacex.UnavailableError)) {
+		a.log.Warnf("seat freed by booking %d not offered to the waitlist: %s", id, err.Error())
+	} else {
+		a.writeServerError(w, err)
+		return
+	}
+
+	// the freed seat goes to the waitlist in the same transaction, under the launch day lock,
+	// so a booking made meanwhile can't take it as well. The locked day comes from the booking read
+	// before the lock, so the booking is read again under it
+	var promoted *db.WaitlistEntry
+	err = a.db.WithLaunchDayLock(ctx, booking.LaunchDate, func(tx db.Storage) error {
+		current, err := tx.Booking(ctx, id)
+		if err != nil {
+			return err
+		}
+		if current.Status != db.BookingStatusConfirmed {
+			return errBookingNotConfirmed
+		}
+		// a concurrent reschedule moved the booking, possibly off the locked day, whose waitlist
+		// would then get the seat without its lock
+		if !sameFlight(bookingFlight(current), bookingFlight(booking)) {
+			return errBookingMoved
+		}
 
-	err = a.db.BookingDelete(ctx, id)
+		cancelled, err := tx.CancelBooking(ctx, id, r.URL.Query().Get("reason"))
+		if err != nil {
+			return err
+		}
+
+		promoted, err = a.promoteFromWaitlist(ctx, tx, sx, cancelled)
+		return err
+	})
 	if err != nil {
-		a.internalServerError(w)
+		if err == db.ErrNotFound || err == errBookingNotConfirmed {
+			// cancelled by a concurrent request
+			a.writeConflict(w, CodeBookingNotConfirmed, "booking is cancelled already")
+			return
+		}
+		if err == errBookingMoved {
+			a.writeConflict(w, CodeBookingChanged, "booking got rescheduled meanwhile, check its current flight and try again")
+			return
+		}
+		a.writeServerError(w, err)
 		return
 	}
+	if promoted != nil {
+		a.log.Infof("waitlist entry %d got booking %d on the seat freed by cancelled booking %d", promoted.ID, *promoted.PromotedBookingID, id)
+	}
+
+	a.writeNoContent(w)
+}
+
+// promoteFromWaitlist books the seat freed by the cancelled or rescheduled booking for the oldest waitlist entry
+// on the same launchpad, day and destination. The schedule may have changed since the freed booking was made,
+// by overrides, SpaceX launches, the scheduler or other bookings of the day, so the entry goes through all
+// the checks of a new booking in tx. Without the SpaceX data they can't run, so nobody is promoted when sx is nil.
+// It returns nil when nobody got the seat.
+func (a *API) promoteFromWaitlist(ctx context.Context, tx db.Storage, sx *spacexData, freed db.Booking) (*db.WaitlistEntry, error) {
+	if sx == nil || !freed.LaunchDate.After(a.now()) {
+		return nil, nil
+	}
+
+	entry, err := tx.OldestWaitlistEntry(ctx, db.BookedLaunch{
+		LaunchDate:    freed.LaunchDate,
+		LaunchpadID:   freed.LaunchpadID,
+		DestinationID: freed.DestinationID,
+	})
+	if err != nil {
+		if err == db.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	err = a.flightSchedulable(ctx, tx, *sx, BookingRequest{
+		LaunchDate:    entry.LaunchDate.Format(dateFormat),
+		LaunchpadID:   entry.LaunchpadID,
+		DestinationID: entry.DestinationID,
+	}, 1)
+	if err != nil {
+		if _, ok := err.(ScheduleError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	booking, err := tx.CreateBooking(ctx, db.Booking{
+		FirstName:     entry.FirstName,
+		LastName:      entry.LastName,
+		Gender:        entry.Gender,
+		Birthday:      entry.Birthday,
+		LaunchpadID:   entry.LaunchpadID,
+		DestinationID: entry.DestinationID,
+		LaunchDate:    entry.LaunchDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.PromoteWaitlistEntry(ctx, entry.ID, booking.ID); err != nil {
+		return nil, err
+	}
 
-	w.WriteHeader(http.StatusNoContent)
+	entry.PromotedBookingID = &booking.ID
+	return &entry, nil
 }
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_BookingDelete(t *testing.T) {
+	launchDate := time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name       string
+		id         string
+		reason     string
+		capacities map[string]int
+		overrides  []db.ScheduleOverride
+		spacex     *spacexMock
+		// movedMeanwhile is where a concurrent request moves booking 1 after it's read and before it's locked
+		movedMeanwhile *db.Flight
+		expectedStatus int
+		expectedBody   string
+		// expectedConfirmed are the IDs of the confirmed bookings after the request
+		expectedConfirmed []int
+		// expectedPromoted are the waitlist entries promoted to a booking, by their booking ID
+		expectedPromoted map[int]int
+	}{
+		{
+			name:              "invalid id",
+			id:                "abc",
+			expectedStatus:    http.StatusBadRequest,
+			expectedBody:      `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"booking id should be an integer and \u003e0","code":"invalid_request"}`,
+			expectedConfirmed: []int{1, 2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name:              "booking not found",
+			id:                "9",
+			expectedStatus:    http.StatusNotFound,
+			expectedBody:      `{"type":"/problems/booking_not_found","title":"Booking not found","status":404,"detail":"booking doesn't exist","code":"booking_not_found"}`,
+			expectedConfirmed: []int{1, 2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name:              "booking cancelled already",
+			id:                "5",
+			expectedStatus:    http.StatusConflict,
+			expectedBody:      `{"type":"/problems/booking_not_confirmed","title":"Booking isn't confirmed","status":409,"detail":"booking is cancelled already","code":"booking_not_confirmed"}`,
+			expectedConfirmed: []int{1, 2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name:              "oldest waitlist entry for the same flight gets the seat",
+			id:                "1",
+			reason:            "changed plans",
+			expectedStatus:    http.StatusNoContent,
+			expectedConfirmed: []int{2, 3, 4, 6},
+			expectedPromoted:  map[int]int{2: 6},
+		},
+		{
+			name:              "nobody waits for the flight",
+			id:                "3",
+			expectedStatus:    http.StatusNoContent,
+			expectedConfirmed: []int{1, 2, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name:              "flight is still full after the capacity was lowered",
+			id:                "1",
+			capacities:        map[string]int{"a": 1},
+			expectedStatus:    http.StatusNoContent,
+			expectedConfirmed: []int{2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name:              "launchpad closed since the booking was made",
+			id:                "1",
+			overrides:         []db.ScheduleOverride{{LaunchDate: launchDate, LaunchpadID: "a", Closed: true}},
+			expectedStatus:    http.StatusNoContent,
+			expectedConfirmed: []int{2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name: "SpaceX uses the launchpad",
+			id:   "1",
+			spacex: &spacexMock{
+				launchpads:       []spacex.Launchpad{{ID: "a"}, {ID: "b"}},
+				upcomingLaunches: []spacex.Launch{{Launchpad: "a", DateUTC: "2022-10-08T05:40:00.000Z"}},
+			},
+			expectedStatus:    http.StatusNoContent,
+			expectedConfirmed: []int{2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name:              "booking is cancelled while SpaceX is unavailable",
+			id:                "1",
+			spacex:            &spacexMock{err: &spacex.UnavailableError{Err: errors.New("spacex is down")}},
+			expectedStatus:    http.StatusNoContent,
+			expectedConfirmed: []int{2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name:              "booking is moved to another day before the lock",
+			id:                "1",
+			movedMeanwhile:    &db.Flight{LaunchDate: launchDate.AddDate(0, 0, 1), LaunchpadID: "a", DestinationID: 4},
+			expectedStatus:    http.StatusConflict,
+			expectedBody:      `{"type":"/problems/booking_changed","title":"Booking changed meanwhile","status":409,"detail":"booking got rescheduled meanwhile, check its current flight and try again","code":"booking_changed"}`,
+			expectedConfirmed: []int{1, 2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+		{
+			name:              "past flight",
+			id:                "4",
+			expectedStatus:    http.StatusConflict,
+			expectedBody:      `{"type":"/problems/launch_in_past","title":"Launch date is in the past","status":409,"detail":"the flight has launched already, the booking can't be cancelled anymore","code":"launch_in_past"}`,
+			expectedConfirmed: []int{1, 2, 3, 4},
+			expectedPromoted:  map[int]int{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		past := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+		cancelledAt := testNow().AddDate(0, 0, -1)
+		dbm := &dbMock{
+			bookings: []db.Booking{
+				{ID: 1, LaunchpadID: "a", DestinationID: 3, LaunchDate: launchDate, Status: db.BookingStatusConfirmed},
+				{ID: 2, LaunchpadID: "a", DestinationID: 3, LaunchDate: launchDate, Status: db.BookingStatusConfirmed},
+				{ID: 3, LaunchpadID: "b", DestinationID: 4, LaunchDate: launchDate, Status: db.BookingStatusConfirmed},
+				{ID: 4, LaunchpadID: "a", DestinationID: 3, LaunchDate: past, Status: db.BookingStatusConfirmed},
+				{ID: 5, LaunchpadID: "a", DestinationID: 3, LaunchDate: launchDate, Status: db.BookingStatusCancelled, CancelledAt: &cancelledAt},
+			},
+			waitlist: []db.WaitlistEntry{
+				{ID: 1, LaunchpadID: "b", DestinationID: 3, LaunchDate: launchDate},
+				{ID: 2, LaunchpadID: "a", DestinationID: 3, LaunchDate: launchDate},
+				{ID: 3, LaunchpadID: "a", DestinationID: 3, LaunchDate: launchDate},
+				{ID: 4, LaunchpadID: "a", DestinationID: 3, LaunchDate: past},
+			},
+			destinations: testDestinations,
+			overrides:    tc.overrides,
+			capacities:   tc.capacities,
+		}
+		if tc.movedMeanwhile != nil {
+			dbm.beforeLock = func() {
+				_, _ = dbm.RescheduleBooking(context.Background(), 1, *tc.movedMeanwhile)
+			}
+		}
+		spacexClient := tc.spacex
+		if spacexClient == nil {
+			spacexClient = &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}}
+		}
+		// on 2022-10-08 the rotation sends launchpad "a" to destination 3
+		a := &API{
+			scheduler: schedule.Rotation{},
+			spacex:    spacexClient,
+			log:       zap.NewNop().Sugar(),
+			db:        dbm,
+			cfg:       Config{DefaultLaunchCapacity: 2},
+			now:       testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/booking/"+tc.id, nil)
+		if tc.reason != "" {
+			req.URL.RawQuery = url.Values{"reason": []string{tc.reason}}.Encode()
+		}
+		a.BookingDelete(resp, withURLParam(req, "id", tc.id))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+
+		var confirmed []int
+		for _, booking := range dbm.bookings {
+			if booking.Status == db.BookingStatusConfirmed {
+				confirmed = append(confirmed, booking.ID)
+				continue
+			}
+			if fmt.Sprint(booking.ID) == tc.id && booking.CancellationReason != tc.reason {
+				t.Logf("unexpected cancellation reason. Got %q, want %q", booking.CancellationReason, tc.reason)
+				t.Fail()
+			}
+		}
+		if fmt.Sprint(confirmed) != fmt.Sprint(tc.expectedConfirmed) {
+			t.Logf("unexpected confirmed bookings. Got %v, want %v", confirmed, tc.expectedConfirmed)
+			t.Fail()
+		}
+		promoted := map[int]int{}
+		for _, entry := range dbm.waitlist {
+			if entry.PromotedBookingID != nil {
+				promoted[entry.ID] = *entry.PromotedBookingID
+			}
+		}
+		if fmt.Sprint(promoted) != fmt.Sprint(tc.expectedPromoted) {
+			t.Logf("unexpected promoted entries. Got %v, want %v", promoted, tc.expectedPromoted)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"space-trouble-bookings-api/db"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (a *API) Booking(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.BookingTimeout)
+	defer cancel()
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id < 1 {
+		a.writeBadRequest(w, CodeInvalidRequest, "booking id should be an integer and >0")
+		return
+	}
+
+	booking, err := a.db.Booking(ctx, id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			a.writeNotFound(w, CodeBookingNotFound, "booking doesn't exist")
+			return
+		}
+
+		a.log.Error(err)
+		a.internalServerError(w)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, newBooking(booking))
+}
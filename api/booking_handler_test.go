@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/db"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func TestAPI_Booking(t *testing.T) {
+	cancelledAt := time.Date(2022, 8, 30, 10, 0, 0, 0, time.UTC)
+	bookings := []db.Booking{
+		{
+			ID:            1,
+			FirstName:     "asd",
+			LastName:      "dsd",
+			Gender:        "male",
+			Birthday:      testNow(),
+			LaunchpadID:   "saffsdf",
+			DestinationID: 2,
+			LaunchDate:    testNow(),
+			Status:        db.BookingStatusConfirmed,
+		},
+		{
+			ID:                 3,
+			FirstName:          "asd",
+			LastName:           "dsd",
+			Gender:             "male",
+			Birthday:           testNow(),
+			LaunchpadID:        "saffsdf",
+			DestinationID:      2,
+			LaunchDate:         testNow(),
+			Status:             db.BookingStatusCancelled,
+			CancelledAt:        &cancelledAt,
+			CancellationReason: "changed plans",
+		},
+	}
+	testCases := []struct {
+		name           string
+		id             string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid id",
+			id:             "abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"booking id should be an integer and \u003e0","code":"invalid_request"}`,
+		},
+		{
+			name:           "booking not found",
+			id:             "2",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"/problems/booking_not_found","title":"Booking not found","status":404,"detail":"booking doesn't exist","code":"booking_not_found"}`,
+		},
+		{
+			name:           "success",
+			id:             "1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":1,"first_name":"asd","last_name":"dsd","gender":"male","birthday":"2022-08-31","launchpad_id":"saffsdf","destination_id":2,"launch_date":"2022-08-31","status":"confirmed"}`,
+		},
+		{
+			name:           "cancelled booking",
+			id:             "3",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":3,"first_name":"asd","last_name":"dsd","gender":"male","birthday":"2022-08-31","launchpad_id":"saffsdf","destination_id":2,"launch_date":"2022-08-31","status":"cancelled","cancelled_at":"2022-08-30T10:00:00Z","cancellation_reason":"changed plans"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			log: zap.NewNop().Sugar(),
+			db: &dbMock{
+				bookings: bookings,
+			},
+		}
+
+		resp := httptest.NewRecorder()
+		a.Booking(resp, withURLParam(httptest.NewRequest("GET", "/booking/"+tc.id, nil), "id", tc.id))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
+
+// withURLParam sets a chi URL parameter on the request, as the router would do.
+func withURLParam(r *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
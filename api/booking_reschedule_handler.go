@@ -0,0 +1,253 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"space-trouble-bookings-api/db"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	// errBookingNotConfirmed is returned when the booking got cancelled while it was being rescheduled.
+	errBookingNotConfirmed = errors.New("booking isn't confirmed")
+	// errBookingMoved is returned when the booking got moved to another flight while it was being rescheduled.
+	errBookingMoved = errors.New("booking moved to another flight")
+)
+
+// RescheduleRequest moves a booking to another flight. Fields left empty keep their current value.
+type RescheduleRequest struct {
+	LaunchDate    string `json:"launch_date"`
+	LaunchpadID   string `json:"launchpad_id"`
+	DestinationID int    `json:"destination_id"`
+}
+
+type BookingChangesResponse struct {
+	Changes []BookingChange `json:"changes"`
+}
+
+type BookingChange struct {
+	Old       Flight    `json:"old"`
+	New       Flight    `json:"new"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type Flight struct {
+	LaunchDate    string `json:"launch_date"`
+	LaunchpadID   string `json:"launchpad_id"`
+	DestinationID int    `json:"destination_id"`
+}
+
+func newFlight(flight db.Flight) Flight {
+	return Flight{
+		LaunchDate:    flight.LaunchDate.Format(dateFormat),
+		LaunchpadID:   flight.LaunchpadID,
+		DestinationID: flight.DestinationID,
+	}
+}
+
+// RescheduleBooking moves the booking to another day, launchpad or destination. The new flight goes through
+// the same checks as a new booking, and the booking keeps its seat on the old flight when it doesn't pass them.
+func (a *API) RescheduleBooking(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.BookingRescheduleTimeout)
+	defer cancel()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		a.writeBadRequest(w, CodeInvalidRequest, "booking id should be an integer and >0")
+		return
+	}
+
+	b, ok := a.readBody(w, r)
+	if !ok {
+		return
+	}
+	req := RescheduleRequest{}
+	if !a.decodeJSON(w, b, &req) {
+		return
+	}
+
+	booking, err := a.db.Booking(ctx, id)
+	if err != nil {
+		if err == db.ErrNotFound {
+			a.writeNotFound(w, CodeBookingNotFound, "booking doesn't exist")
+			return
+		}
+		a.writeServerError(w, err)
+		return
+	}
+	if booking.Status != db.BookingStatusConfirmed {
+		a.writeConflict(w, CodeBookingNotConfirmed, fmt.Sprintf("booking is %s, only confirmed bookings can be rescheduled", booking.Status))
+		return
+	}
+
+	old := bookingFlight(booking)
+	flight := old
+	if req.LaunchDate != "" {
+		flight.LaunchDate, err = time.Parse(dateFormat, req.LaunchDate)
+		if err != nil {
+			a.writeBadRequest(w, CodeInvalidRequest, fmt.Sprintf("Invalid launch date. Should be in format YYYY-MM-DD: %s", err.Error()))
+			return
+		}
+	}
+	if req.LaunchpadID != "" {
+		flight.LaunchpadID = req.LaunchpadID
+	}
+	if req.DestinationID != 0 {
+		flight.DestinationID = req.DestinationID
+	}
+	if sameFlight(flight, old) {
+		a.writeConflict(w, CodeSameFlight, "The booking is on that flight already")
+		return
+	}
+	if old.LaunchDate.Before(a.now()) || flight.LaunchDate.Before(a.now()) {
+		a.writeBadRequest(w, CodeLaunchInPast, "Travels to the past are still in development. Set a launch day in future for now")
+		return
+	}
+
+	sx, err := a.getSpaceXData(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	// both days are locked: the new one for the schedule checks, the old one for the seat given to the waitlist.
+	// The old day comes from the booking read before the lock, so the booking is read again under it
+	var rescheduled db.Booking
+	var promoted *db.WaitlistEntry
+	err = a.db.WithLaunchDaysLock(ctx, []time.Time{old.LaunchDate, flight.LaunchDate}, func(tx db.Storage) error {
+		current, err := tx.Booking(ctx, id)
+		if err != nil {
+			return err
+		}
+		if current.Status != db.BookingStatusConfirmed {
+			return errBookingNotConfirmed
+		}
+		// a concurrent reschedule moved the booking off the locked day, and the fields left empty
+		// in the request would be taken from a flight it isn't on anymore
+		currentFlight := bookingFlight(current)
+		if !sameFlight(currentFlight, old) {
+			return errBookingMoved
+		}
+
+		// the booking itself mustn't count as taking a seat or locking the day on the new flight
+		err = a.flightSchedulable(ctx, withoutBooking{Storage: tx, booking: current}, sx, BookingRequest{
+			LaunchDate:    flight.LaunchDate.Format(dateFormat),
+			LaunchpadID:   flight.LaunchpadID,
+			DestinationID: flight.DestinationID,
+		}, 1)
+		if err != nil {
+			return err
+		}
+
+		rescheduled, err = tx.RescheduleBooking(ctx, id, flight)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBookingChange(ctx, db.BookingChange{BookingID: id, Old: currentFlight, New: flight})
+		if err != nil {
+			return err
+		}
+
+		promoted, err = a.promoteFromWaitlist(ctx, tx, &sx, current)
+		return err
+	})
+	if err != nil {
+		if scheduleErr, ok := err.(ScheduleError); ok {
+			a.writeScheduleError(w, scheduleErr)
+			return
+		}
+		if err == db.ErrNotFound || err == errBookingNotConfirmed {
+			a.writeConflict(w, CodeBookingNotConfirmed, "booking got cancelled meanwhile")
+			return
+		}
+		if err == errBookingMoved {
+			a.writeConflict(w, CodeBookingChanged, "booking got rescheduled meanwhile, check its current flight and try again")
+			return
+		}
+
+		a.writeServerError(w, err)
+		return
+	}
+	if promoted != nil {
+		a.log.Infof("waitlist entry %d got booking %d on the seat freed by rescheduled booking %d", promoted.ID, *promoted.PromotedBookingID, id)
+	}
+
+	a.writeJSON(w, http.StatusOK, newBooking(rescheduled))
+}
+
+func bookingFlight(b db.Booking) db.Flight {
+	return db.Flight{LaunchDate: b.LaunchDate, LaunchpadID: b.LaunchpadID, DestinationID: b.DestinationID}
+}
+
+func sameFlight(a, b db.Flight) bool {
+	return a.LaunchDate.Equal(b.LaunchDate) && a.LaunchpadID == b.LaunchpadID && a.DestinationID == b.DestinationID
+}
+
+// BookingChanges lists the flights the booking was moved between, oldest change first.
+func (a *API) BookingChanges(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.BookingTimeout)
+	defer cancel()
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		a.writeBadRequest(w, CodeInvalidRequest, "booking id should be an integer and >0")
+		return
+	}
+
+	if _, err = a.db.Booking(ctx, id); err != nil {
+		if err == db.ErrNotFound {
+			a.writeNotFound(w, CodeBookingNotFound, "booking doesn't exist")
+			return
+		}
+		a.writeServerError(w, err)
+		return
+	}
+
+	changes, err := a.db.BookingChanges(ctx, id)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	respChanges := make([]BookingChange, 0, len(changes))
+	for _, change := range changes {
+		respChanges = append(respChanges, BookingChange{
+			Old:       newFlight(change.Old),
+			New:       newFlight(change.New),
+			ChangedAt: change.ChangedAt,
+		})
+	}
+
+	a.writeJSON(w, http.StatusOK, BookingChangesResponse{Changes: respChanges})
+}
+
+// withoutBooking hides the booking from the booked launches, as if it wasn't made yet.
+type withoutBooking struct {
+	db.Storage
+	booking db.Booking
+}
+
+func (s withoutBooking) BookedLaunches(ctx context.Context, from, to time.Time) ([]db.BookedLaunch, error) {
+	launches, err := s.Storage.BookedLaunches(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]db.BookedLaunch, 0, len(launches))
+	for _, launch := range launches {
+		if launch.LaunchDate.Equal(s.booking.LaunchDate) && launch.LaunchpadID == s.booking.LaunchpadID &&
+			launch.DestinationID == s.booking.DestinationID {
+			launch.Passengers--
+			if launch.Passengers == 0 {
+				continue
+			}
+		}
+		filtered = append(filtered, launch)
+	}
+	return filtered, nil
+}
@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_RescheduleBooking(t *testing.T) {
+	// on 2022-10-08 the rotation sends launchpad "a" to destination 3 and launchpad "b" to destination 4,
+	// on 2022-10-09 launchpad "a" to destination 4 and launchpad "b" to destination 5
+	testCases := []struct {
+		name string
+		id   string
+		body string
+		// movedMeanwhile is where a concurrent request moves booking 1 after it's read and before it's locked
+		movedMeanwhile *db.Flight
+		expectedStatus int
+		expectedBody   string
+		// expectedBooking is the flight of booking 1 after the request
+		expectedBooking  string
+		expectedChanges  int
+		expectedPromoted map[int]int
+	}{
+		{
+			name:             "booking not found",
+			id:               "9",
+			body:             `{"launch_date": "2022-10-09"}`,
+			expectedStatus:   http.StatusNotFound,
+			expectedBody:     `{"type":"/problems/booking_not_found","title":"Booking not found","status":404,"detail":"booking doesn't exist","code":"booking_not_found"}`,
+			expectedBooking:  "2022-10-08 a 3",
+			expectedPromoted: map[int]int{},
+		},
+		{
+			name:             "cancelled booking",
+			id:               "2",
+			body:             `{"launch_date": "2022-10-09"}`,
+			expectedStatus:   http.StatusConflict,
+			expectedBody:     `{"type":"/problems/booking_not_confirmed","title":"Booking isn't confirmed","status":409,"detail":"booking is cancelled, only confirmed bookings can be rescheduled","code":"booking_not_confirmed"}`,
+			expectedBooking:  "2022-10-08 a 3",
+			expectedPromoted: map[int]int{},
+		},
+		{
+			name:             "invalid launch date",
+			id:               "1",
+			body:             `{"launch_date": "tomorrow"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"Invalid launch date. Should be in format YYYY-MM-DD: parsing time \"tomorrow\" as \"2006-01-02\": cannot parse \"tomorrow\" as \"2006\"","code":"invalid_request"}`,
+			expectedBooking:  "2022-10-08 a 3",
+			expectedPromoted: map[int]int{},
+		},
+		{
+			name:             "same flight",
+			id:               "1",
+			body:             `{"launch_date": "2022-10-08", "launchpad_id": "a"}`,
+			expectedStatus:   http.StatusConflict,
+			expectedBody:     `{"type":"/problems/same_flight","title":"Booking is on that flight already","status":409,"detail":"The booking is on that flight already","code":"same_flight"}`,
+			expectedBooking:  "2022-10-08 a 3",
+			expectedPromoted: map[int]int{},
+		},
+		{
+			name:             "past date",
+			id:               "1",
+			body:             `{"launch_date": "2022-08-01"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedBody:     `{"type":"/problems/launch_in_past","title":"Launch date is in the past","status":400,"detail":"Travels to the past are still in development. Set a launch day in future for now","code":"launch_in_past"}`,
+			expectedBooking:  "2022-10-08 a 3",
+			expectedPromoted: map[int]int{},
+		},
+		{
+			name:             "destination isn't scheduled on the new flight",
+			id:               "1",
+			body:             `{"destination_id": 4}`,
+			expectedStatus:   http.StatusConflict,
+			expectedBody:     `{"type":"/problems/destination_not_scheduled","title":"Destination isn't scheduled","status":409,"detail":"Flight can't be booked: No launches available for destination 4(Asteroid Belt) on launchpad a on 2022-10-08","code":"destination_not_scheduled"}`,
+			expectedBooking:  "2022-10-08 a 3",
+			expectedPromoted: map[int]int{},
+		},
+		{
+			name:             "new day is locked to another destination",
+			id:               "1",
+			body:             `{"launch_date": "2022-10-09", "destination_id": 4}`,
+			expectedStatus:   http.StatusConflict,
+			expectedBody:     `{"type":"/problems/destination_locked","title":"Day is locked to another destination","status":409,"detail":"Flight can't be booked: On that day bookings only for destination 5 are allowed","code":"destination_locked"}`,
+			expectedBooking:  "2022-10-08 a 3",
+			expectedPromoted: map[int]int{},
+		},
+		{
+			name:             "move to another day frees the seat for the waitlist",
+			id:               "1",
+			body:             `{"launch_date": "2022-10-09", "launchpad_id": "b", "destination_id": 5}`,
+			expectedStatus:   http.StatusOK,
+			expectedBody:     `{"id":1,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"b","destination_id":5,"launch_date":"2022-10-09","status":"confirmed"}`,
+			expectedBooking:  "2022-10-09 b 5",
+			expectedChanges:  1,
+			expectedPromoted: map[int]int{1: 4},
+		},
+		{
+			// the booking itself doesn't lock the day to destination 3 anymore, and so the waitlist can't get its seat
+			name:             "move within the day",
+			id:               "1",
+			body:             `{"launchpad_id": "b", "destination_id": 4}`,
+			expectedStatus:   http.StatusOK,
+			expectedBody:     `{"id":1,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"b","destination_id":4,"launch_date":"2022-10-08","status":"confirmed"}`,
+			expectedBooking:  "2022-10-08 b 4",
+			expectedChanges:  1,
+			expectedPromoted: map[int]int{},
+		},
+		{
+			// the request read the booking on 2022-10-08 and locked that day, but it's on 2022-10-09 by now
+			name: "booking moved by a concurrent request",
+			id:   "1",
+			body: `{"launch_date": "2022-10-10"}`,
+			movedMeanwhile: &db.Flight{
+				LaunchDate:    time.Date(2022, 10, 9, 0, 0, 0, 0, time.UTC),
+				LaunchpadID:   "b",
+				DestinationID: 5,
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedBody:     `{"type":"/problems/booking_changed","title":"Booking changed meanwhile","status":409,"detail":"booking got rescheduled meanwhile, check its current flight and try again","code":"booking_changed"}`,
+			expectedBooking:  "2022-10-09 b 5",
+			expectedPromoted: map[int]int{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		day := time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)
+		nextDay := day.AddDate(0, 0, 1)
+		birthday := time.Date(1993, 4, 18, 0, 0, 0, 0, time.UTC)
+		dbm := &dbMock{
+			destinations: testDestinations,
+			bookings: []db.Booking{
+				{ID: 1, FirstName: "fname", LastName: "lname", Gender: "male", Birthday: birthday, LaunchpadID: "a", DestinationID: 3, LaunchDate: day, Status: db.BookingStatusConfirmed},
+				{ID: 2, LaunchpadID: "a", DestinationID: 3, LaunchDate: day, Status: db.BookingStatusCancelled},
+				{ID: 3, LaunchpadID: "b", DestinationID: 5, LaunchDate: nextDay, Status: db.BookingStatusConfirmed},
+			},
+			waitlist: []db.WaitlistEntry{
+				{ID: 1, LaunchpadID: "a", DestinationID: 3, LaunchDate: day},
+			},
+		}
+		if tc.movedMeanwhile != nil {
+			dbm.beforeLock = func() {
+				_, _ = dbm.RescheduleBooking(context.Background(), 1, *tc.movedMeanwhile)
+			}
+		}
+		a := &API{
+			spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}},
+			log:       zap.NewNop().Sugar(),
+			db:        dbm,
+			scheduler: schedule.Rotation{},
+			now:       testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/booking/"+tc.id+"/reschedule", strings.NewReader(tc.body))
+		a.RescheduleBooking(resp, withURLParam(req, "id", tc.id))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+
+		booking := dbm.bookings[0]
+		flight := fmt.Sprintf("%s %s %d", booking.LaunchDate.Format(dateFormat), booking.LaunchpadID, booking.DestinationID)
+		if flight != tc.expectedBooking {
+			t.Logf("unexpected booking flight. Got %s, want %s", flight, tc.expectedBooking)
+			t.Fail()
+		}
+		if len(dbm.changes) != tc.expectedChanges {
+			t.Logf("unexpected number of changes. Got %d, want %d", len(dbm.changes), tc.expectedChanges)
+			t.Fail()
+		}
+		promoted := map[int]int{}
+		for _, entry := range dbm.waitlist {
+			if entry.PromotedBookingID != nil {
+				promoted[entry.ID] = *entry.PromotedBookingID
+			}
+		}
+		if fmt.Sprint(promoted) != fmt.Sprint(tc.expectedPromoted) {
+			t.Logf("unexpected promoted entries. Got %v, want %v", promoted, tc.expectedPromoted)
+			t.Fail()
+		}
+	}
+}
+
+func TestAPI_BookingChanges(t *testing.T) {
+	testCases := []struct {
+		name           string
+		id             string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "booking not found",
+			id:             "9",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"/problems/booking_not_found","title":"Booking not found","status":404,"detail":"booking doesn't exist","code":"booking_not_found"}`,
+		},
+		{
+			name:           "booking history",
+			id:             "1",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"changes":[{"old":{"launch_date":"2022-10-08","launchpad_id":"a","destination_id":3},` +
+				`"new":{"launch_date":"2022-10-09","launchpad_id":"b","destination_id":5},"changed_at":"2022-08-31T12:00:00Z"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			log: zap.NewNop().Sugar(),
+			db: &dbMock{
+				bookings: []db.Booking{{ID: 1, LaunchpadID: "b", DestinationID: 5, Status: db.BookingStatusConfirmed}},
+				changes: []db.BookingChange{{
+					ID:        1,
+					BookingID: 1,
+					Old:       db.Flight{LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 3},
+					New:       db.Flight{LaunchDate: time.Date(2022, 10, 9, 0, 0, 0, 0, time.UTC), LaunchpadID: "b", DestinationID: 5},
+					ChangedAt: testNow(),
+				}},
+			},
+		}
+
+		resp := httptest.NewRecorder()
+		a.BookingChanges(resp, withURLParam(httptest.NewRequest("GET", "/booking/"+tc.id+"/changes", nil), "id", tc.id))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
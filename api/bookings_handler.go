@@ -1,10 +1,11 @@
 package api
 
 import (
-	"context"
+	"fmt"
 	"net/http"
 	"space-trouble-bookings-api/db"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,41 +22,61 @@ type Booking struct {
 	LaunchpadID   string `json:"launchpad_id"`
 	DestinationID int    `json:"destination_id"`
 	LaunchDate    string `json:"launch_date"`
+	Status        string `json:"status"`
+	// CancelledAt and CancellationReason are only set for cancelled bookings
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	CancellationReason string     `json:"cancellation_reason,omitempty"`
+	GroupID            *int       `json:"group_id,omitempty"`
 }
 
 func (a *API) Bookings(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	ctx, cancel := requestContext(r, a.cfg.BookingsTimeout)
 	defer cancel()
 
 	bookingsFilter := db.BookingsFilter{}
 	q := r.URL.Query()
+	v := &validator{}
 	if q.Has("launch_date") {
 		launchDay, err := time.Parse(dateFormat, q.Get("launch_date"))
 		if err != nil {
-			a.writeBadRequest(w, ErrorResponse{Message: "launch_date should be in format YYYY-MM-DD"})
-			return
+			v.add("launch_date", CodeInvalidFormat, "launch_date should be in format YYYY-MM-DD")
 		}
 		bookingsFilter.LaunchDate = launchDay
 	}
 
+	if q.Has("status") {
+		status := q.Get("status")
+		if !contains(db.BookingStatuses, status) {
+			v.add("status", CodeInvalidValue, fmt.Sprintf("status should be one of %s", strings.Join(db.BookingStatuses, ", ")))
+		}
+		bookingsFilter.Status = status
+	}
+
 	if q.Has("offset") {
 		offset, err := strconv.Atoi(q.Get("offset"))
-		if err != nil || offset < 0 {
-			a.writeBadRequest(w, ErrorResponse{Message: "offset should be an integer and be more than 0"})
-			return
+		if err != nil {
+			v.add("offset", CodeInvalidFormat, "offset should be an integer and be more than 0")
+		} else if offset < 0 {
+			v.add("offset", CodeOutOfRange, "offset should be an integer and be more than 0")
 		}
 		bookingsFilter.Offset = offset
 	}
 
 	if q.Has("limit") {
 		limit, err := strconv.Atoi(q.Get("limit"))
-		if err != nil || limit < 1 || limit > 300 {
-			a.writeBadRequest(w, ErrorResponse{Message: "limit should be an integer and be more that 0 and less or equal 300"})
-			return
+		if err != nil {
+			v.add("limit", CodeInvalidFormat, "limit should be an integer and be more that 0 and less or equal 300")
+		} else if limit < 1 || limit > 300 {
+			v.add("limit", CodeOutOfRange, "limit should be an integer and be more that 0 and less or equal 300")
 		}
 		bookingsFilter.Limit = limit
 	}
 
+	if !v.valid() {
+		a.writeValidationError(w, v)
+		return
+	}
+
 	bookings, err := a.db.Bookings(ctx, bookingsFilter)
 	if err != nil {
 		a.log.Error(err)
@@ -65,17 +86,34 @@ func (a *API) Bookings(w http.ResponseWriter, r *http.Request) {
 
 	respBookings := make([]Booking, 0, len(bookings))
 	for _, booking := range bookings {
-		respBookings = append(respBookings, Booking{
-			ID:            booking.ID,
-			FirstName:     booking.FirstName,
-			LastName:      booking.LastName,
-			Gender:        booking.Gender,
-			Birthday:      booking.Birthday.Format(dateFormat),
-			LaunchpadID:   booking.LaunchpadID,
-			DestinationID: booking.DestinationID,
-			LaunchDate:    booking.LaunchDate.Format(dateFormat),
-		})
+		respBookings = append(respBookings, newBooking(booking))
 	}
 
-	a.writeJSONResponse(w, BookingsResponse{Bookings: respBookings})
+	a.writeJSON(w, http.StatusOK, BookingsResponse{Bookings: respBookings})
+}
+
+func newBooking(booking db.Booking) Booking {
+	return Booking{
+		ID:                 booking.ID,
+		FirstName:          booking.FirstName,
+		LastName:           booking.LastName,
+		Gender:             booking.Gender,
+		Birthday:           booking.Birthday.Format(dateFormat),
+		LaunchpadID:        booking.LaunchpadID,
+		DestinationID:      booking.DestinationID,
+		LaunchDate:         booking.LaunchDate.Format(dateFormat),
+		Status:             booking.Status,
+		CancelledAt:        booking.CancelledAt,
+		CancellationReason: booking.CancellationReason,
+		GroupID:            booking.GroupID,
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
@@ -12,6 +12,7 @@ import (
 )
 
 func TestAPI_Bookings(t *testing.T) {
+	cancelledAt := time.Date(2022, 8, 30, 10, 0, 0, 0, time.UTC)
 	testCases := []struct {
 		name           string
 		queryParams    url.Values
@@ -28,24 +29,26 @@ func TestAPI_Bookings(t *testing.T) {
 					FirstName:     "asd",
 					LastName:      "dsd",
 					Gender:        "male",
-					Birthday:      time.Now(),
+					Birthday:      testNow(),
 					LaunchpadID:   "saffsdf",
 					DestinationID: 2,
-					LaunchDate:    time.Now(),
+					LaunchDate:    testNow(),
+					Status:        db.BookingStatusConfirmed,
 				},
 				{
 					ID:            2,
 					FirstName:     "dsf",
 					LastName:      "tyyy",
 					Gender:        "male",
-					Birthday:      time.Now(),
+					Birthday:      testNow(),
 					LaunchpadID:   "saffsdf",
 					DestinationID: 5,
-					LaunchDate:    time.Now(),
+					LaunchDate:    testNow(),
+					Status:        db.BookingStatusConfirmed,
 				},
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":"offset should be an integer and be more than 0"}`,
+			expectedBody:   `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"offset should be an integer and be more than 0","code":"validation_failed","errors":[{"field":"offset","code":"invalid_format","detail":"offset should be an integer and be more than 0"}]}`,
 		},
 		{
 			name:        "invalid limit query param",
@@ -56,24 +59,26 @@ func TestAPI_Bookings(t *testing.T) {
 					FirstName:     "asd",
 					LastName:      "dsd",
 					Gender:        "male",
-					Birthday:      time.Now(),
+					Birthday:      testNow(),
 					LaunchpadID:   "saffsdf",
 					DestinationID: 2,
-					LaunchDate:    time.Now(),
+					LaunchDate:    testNow(),
+					Status:        db.BookingStatusConfirmed,
 				},
 				{
 					ID:            2,
 					FirstName:     "dsf",
 					LastName:      "tyyy",
 					Gender:        "male",
-					Birthday:      time.Now(),
+					Birthday:      testNow(),
 					LaunchpadID:   "saffsdf",
 					DestinationID: 5,
-					LaunchDate:    time.Now(),
+					LaunchDate:    testNow(),
+					Status:        db.BookingStatusConfirmed,
 				},
 			},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"message":"limit should be an integer and be more that 0 and less or equal 300"}`,
+			expectedBody:   `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"limit should be an integer and be more that 0 and less or equal 300","code":"validation_failed","errors":[{"field":"limit","code":"invalid_format","detail":"limit should be an integer and be more that 0 and less or equal 300"}]}`,
 		},
 		{
 			name:        "success",
@@ -84,24 +89,73 @@ func TestAPI_Bookings(t *testing.T) {
 					FirstName:     "asd",
 					LastName:      "dsd",
 					Gender:        "male",
-					Birthday:      time.Now(),
+					Birthday:      testNow(),
 					LaunchpadID:   "saffsdf",
 					DestinationID: 2,
-					LaunchDate:    time.Now(),
+					LaunchDate:    testNow(),
+					Status:        db.BookingStatusConfirmed,
 				},
 				{
 					ID:            2,
 					FirstName:     "dsf",
 					LastName:      "tyyy",
 					Gender:        "male",
-					Birthday:      time.Now(),
+					Birthday:      testNow(),
 					LaunchpadID:   "saffsdf",
 					DestinationID: 5,
-					LaunchDate:    time.Now(),
+					LaunchDate:    testNow(),
+					Status:        db.BookingStatusConfirmed,
 				},
 			},
 			expectedStatus: http.StatusOK,
-			expectedBody:   `{"bookings":[{"id":1,"first_name":"asd","last_name":"dsd","gender":"male","birthday":"2022-08-31","launchpad_id":"saffsdf","destination_id":2,"launch_date":"2022-08-31"},{"id":2,"first_name":"dsf","last_name":"tyyy","gender":"male","birthday":"2022-08-31","launchpad_id":"saffsdf","destination_id":5,"launch_date":"2022-08-31"}]}`,
+			expectedBody:   `{"bookings":[{"id":1,"first_name":"asd","last_name":"dsd","gender":"male","birthday":"2022-08-31","launchpad_id":"saffsdf","destination_id":2,"launch_date":"2022-08-31","status":"confirmed"},{"id":2,"first_name":"dsf","last_name":"tyyy","gender":"male","birthday":"2022-08-31","launchpad_id":"saffsdf","destination_id":5,"launch_date":"2022-08-31","status":"confirmed"}]}`,
+		},
+		{
+			name:           "invalid status",
+			queryParams:    url.Values{"status": []string{"pending"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"status should be one of confirmed, cancelled, flown, no_show","code":"validation_failed","errors":[{"field":"status","code":"invalid_value","detail":"status should be one of confirmed, cancelled, flown, no_show"}]}`,
+		},
+		{
+			name:           "all invalid query params are reported",
+			queryParams:    url.Values{"launch_date": []string{"tomorrow"}, "offset": []string{"-1"}, "limit": []string{"0"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"launch_date should be in format YYYY-MM-DD; offset should be an integer and be more than 0; ` +
+				`limit should be an integer and be more that 0 and less or equal 300","code":"validation_failed",` +
+				`"errors":[{"field":"launch_date","code":"invalid_format","detail":"launch_date should be in format YYYY-MM-DD"},` +
+				`{"field":"offset","code":"out_of_range","detail":"offset should be an integer and be more than 0"},` +
+				`{"field":"limit","code":"out_of_range","detail":"limit should be an integer and be more that 0 and less or equal 300"}]}`,
+		},
+		{
+			name:        "filter by status",
+			queryParams: url.Values{"status": []string{"cancelled"}},
+			bookings: []db.Booking{
+				{
+					ID:            1,
+					FirstName:     "asd",
+					LastName:      "dsd",
+					Gender:        "male",
+					Birthday:      testNow(),
+					LaunchpadID:   "saffsdf",
+					DestinationID: 2,
+					LaunchDate:    testNow(),
+					Status:        db.BookingStatusConfirmed,
+				},
+				{
+					ID:            2,
+					FirstName:     "dsf",
+					LastName:      "tyyy",
+					Gender:        "male",
+					Birthday:      testNow(),
+					LaunchpadID:   "saffsdf",
+					DestinationID: 5,
+					LaunchDate:    testNow(),
+					Status:        db.BookingStatusCancelled,
+					CancelledAt:   &cancelledAt,
+				},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"bookings":[{"id":2,"first_name":"dsf","last_name":"tyyy","gender":"male","birthday":"2022-08-31","launchpad_id":"saffsdf","destination_id":5,"launch_date":"2022-08-31","status":"cancelled","cancelled_at":"2022-08-30T10:00:00Z"}]}`,
 		},
 	}
 
@@ -123,6 +177,7 @@ func TestAPI_Bookings(t *testing.T) {
 			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
 			t.Fail()
 		}
+		checkContentType(t, resp)
 		if tc.expectedBody != resp.Body.String() {
 			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
 			t.Fail()
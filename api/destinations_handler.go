@@ -0,0 +1,156 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"space-trouble-bookings-api/db"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const maxDestinationNameLen = 50
+
+type DestinationsResponse struct {
+	Destinations []Destination `json:"destinations"`
+}
+
+type Destination struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type DestinationRequest struct {
+	Name string `json:"name"`
+}
+
+func (a *API) Destinations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.DestinationsTimeout)
+	defer cancel()
+
+	destinations, err := a.db.Destinations(ctx)
+	if err != nil {
+		a.log.Error(err)
+		a.internalServerError(w)
+		return
+	}
+
+	respDestinations := make([]Destination, 0, len(destinations))
+	for _, destination := range destinations {
+		respDestinations = append(respDestinations, Destination(destination))
+	}
+
+	a.writeJSON(w, http.StatusOK, DestinationsResponse{Destinations: respDestinations})
+}
+
+func (a *API) CreateDestination(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.DestinationsTimeout)
+	defer cancel()
+
+	name, ok := a.readDestinationName(w, r)
+	if !ok {
+		return
+	}
+
+	destination, err := a.db.CreateDestination(ctx, name)
+	if err != nil {
+		a.writeDestinationError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/destinations/%d", destination.ID))
+	a.writeJSON(w, http.StatusCreated, Destination(destination))
+}
+
+func (a *API) UpdateDestination(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.DestinationsTimeout)
+	defer cancel()
+
+	id, ok := a.destinationID(w, r)
+	if !ok {
+		return
+	}
+	name, ok := a.readDestinationName(w, r)
+	if !ok {
+		return
+	}
+
+	destination, err := a.db.UpdateDestination(ctx, id, name)
+	if err != nil {
+		a.writeDestinationError(w, err)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, Destination(destination))
+}
+
+// DeleteDestination deletes the destination. It's refused while bookings, the timetable, schedule overrides
+// or the waitlist use it from today on.
+func (a *API) DeleteDestination(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.DestinationsTimeout)
+	defer cancel()
+
+	id, ok := a.destinationID(w, r)
+	if !ok {
+		return
+	}
+
+	now := a.now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if err := a.db.DeleteDestination(ctx, id, today); err != nil {
+		a.writeDestinationError(w, err)
+		return
+	}
+
+	a.writeNoContent(w)
+}
+
+func (a *API) destinationID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id < 1 {
+		a.writeBadRequest(w, CodeInvalidRequest, "destination id should be an integer and >0")
+		return 0, false
+	}
+	return id, true
+}
+
+func (a *API) readDestinationName(w http.ResponseWriter, r *http.Request) (string, bool) {
+	b, ok := a.readBody(w, r)
+	if !ok {
+		return "", false
+	}
+
+	req := DestinationRequest{}
+	if !a.decodeJSON(w, b, &req) {
+		return "", false
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if len(name) == 0 {
+		a.writeBadRequest(w, CodeInvalidRequest, "field name can't be empty")
+		return "", false
+	}
+	if len(name) > maxDestinationNameLen {
+		a.writeBadRequest(w, CodeInvalidRequest, "field name can't be longer than 50 characters")
+		return "", false
+	}
+
+	return name, true
+}
+
+func (a *API) writeDestinationError(w http.ResponseWriter, err error) {
+	switch err {
+	case db.ErrNotFound:
+		a.writeNotFound(w, CodeDestinationNotFound, "destination doesn't exist")
+	case db.ErrAlreadyExists:
+		a.writeConflict(w, CodeDestinationExists, "destination with this name already exists")
+	case db.ErrInUse:
+		a.writeConflict(w, CodeDestinationInUse,
+			"destination is used by upcoming bookings, timetable entries, schedule overrides or waitlist entries and can't be deleted")
+	default:
+		a.log.Error(err)
+		a.internalServerError(w)
+	}
+}
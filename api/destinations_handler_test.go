@@ -0,0 +1,191 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/db"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_Destinations(t *testing.T) {
+	testCases := []struct {
+		name             string
+		call             func(a *API, w http.ResponseWriter, r *http.Request)
+		method           string
+		id               string
+		body             string
+		bookings         []db.Booking
+		timetable        []db.TimetableEntry
+		overrides        []db.ScheduleOverride
+		waitlist         []db.WaitlistEntry
+		expectedStatus   int
+		expectedBody     string
+		expectedLocation string
+	}{
+		{
+			name:           "list destinations",
+			call:           (*API).Destinations,
+			method:         "GET",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"destinations":[{"id":1,"name":"Mars"},{"id":2,"name":"Moon"}]}`,
+		},
+		{
+			name:             "create destination",
+			call:             (*API).CreateDestination,
+			method:           "POST",
+			body:             `{"name": " Europa "}`,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     `{"id":3,"name":"Europa"}`,
+			expectedLocation: "/destinations/3",
+		},
+		{
+			name:           "create destination with empty name",
+			call:           (*API).CreateDestination,
+			method:         "POST",
+			body:           `{"name": "  "}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"field name can't be empty","code":"invalid_request"}`,
+		},
+		{
+			name:           "create destination with too long name",
+			call:           (*API).CreateDestination,
+			method:         "POST",
+			body:           `{"name": "` + strings.Repeat("a", 51) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"field name can't be longer than 50 characters","code":"invalid_request"}`,
+		},
+		{
+			name:           "create duplicate destination",
+			call:           (*API).CreateDestination,
+			method:         "POST",
+			body:           `{"name": "Mars"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_exists","title":"Destination already exists","status":409,"detail":"destination with this name already exists","code":"destination_exists"}`,
+		},
+		{
+			name:           "rename destination",
+			call:           (*API).UpdateDestination,
+			method:         "PATCH",
+			id:             "2",
+			body:           `{"name": "Luna"}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"id":2,"name":"Luna"}`,
+		},
+		{
+			name:           "rename to an existing name",
+			call:           (*API).UpdateDestination,
+			method:         "PATCH",
+			id:             "2",
+			body:           `{"name": "Mars"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_exists","title":"Destination already exists","status":409,"detail":"destination with this name already exists","code":"destination_exists"}`,
+		},
+		{
+			name:           "rename nonexistent destination",
+			call:           (*API).UpdateDestination,
+			method:         "PATCH",
+			id:             "9",
+			body:           `{"name": "Luna"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"/problems/destination_not_found","title":"Destination not found","status":404,"detail":"destination doesn't exist","code":"destination_not_found"}`,
+		},
+		{
+			name:           "delete destination with past bookings only",
+			call:           (*API).DeleteDestination,
+			method:         "DELETE",
+			id:             "2",
+			bookings:       []db.Booking{{ID: 1, DestinationID: 2, LaunchDate: time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)}},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "delete destination with upcoming bookings",
+			call:           (*API).DeleteDestination,
+			method:         "DELETE",
+			id:             "2",
+			bookings:       []db.Booking{{ID: 1, DestinationID: 2, LaunchDate: time.Date(2022, 8, 31, 0, 0, 0, 0, time.UTC)}},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_in_use","title":"Destination is in use","status":409,"detail":"destination is used by upcoming bookings, timetable entries, schedule overrides or waitlist entries and can't be deleted","code":"destination_in_use"}`,
+		},
+		{
+			name:           "delete destination pinned in the timetable",
+			call:           (*API).DeleteDestination,
+			method:         "DELETE",
+			id:             "2",
+			timetable:      []db.TimetableEntry{{LaunchDate: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 2}},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_in_use","title":"Destination is in use","status":409,"detail":"destination is used by upcoming bookings, timetable entries, schedule overrides or waitlist entries and can't be deleted","code":"destination_in_use"}`,
+		},
+		{
+			name:           "delete destination forced by a schedule override",
+			call:           (*API).DeleteDestination,
+			method:         "DELETE",
+			id:             "2",
+			overrides:      []db.ScheduleOverride{{LaunchDate: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: intPtr(2)}},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_in_use","title":"Destination is in use","status":409,"detail":"destination is used by upcoming bookings, timetable entries, schedule overrides or waitlist entries and can't be deleted","code":"destination_in_use"}`,
+		},
+		{
+			name:           "delete destination with a waitlist",
+			call:           (*API).DeleteDestination,
+			method:         "DELETE",
+			id:             "2",
+			waitlist:       []db.WaitlistEntry{{ID: 1, LaunchDate: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 2}},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_in_use","title":"Destination is in use","status":409,"detail":"destination is used by upcoming bookings, timetable entries, schedule overrides or waitlist entries and can't be deleted","code":"destination_in_use"}`,
+		},
+		{
+			name:           "delete destination used in the past only",
+			call:           (*API).DeleteDestination,
+			method:         "DELETE",
+			id:             "2",
+			timetable:      []db.TimetableEntry{{LaunchDate: time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 2}},
+			waitlist:       []db.WaitlistEntry{{ID: 1, LaunchDate: time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 2}},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "delete with invalid id",
+			call:           (*API).DeleteDestination,
+			method:         "DELETE",
+			id:             "abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"destination id should be an integer and \u003e0","code":"invalid_request"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			log: zap.NewNop().Sugar(),
+			db: &dbMock{
+				destinations: []db.Destination{{ID: 1, Name: "Mars"}, {ID: 2, Name: "Moon"}},
+				bookings:     tc.bookings,
+				timetable:    tc.timetable,
+				overrides:    tc.overrides,
+				waitlist:     tc.waitlist,
+			},
+			now: testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, "/destinations/"+tc.id, strings.NewReader(tc.body))
+		tc.call(a, resp, withURLParam(req, "id", tc.id))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+		if tc.expectedLocation != resp.Header().Get("Location") {
+			t.Logf("unexpected Location header. Got %s, want %s", resp.Header().Get("Location"), tc.expectedLocation)
+			t.Fail()
+		}
+	}
+}
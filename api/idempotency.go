@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"space-trouble-bookings-api/db"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+	// saveResponseTimeout limits storing the response after the handler is done
+	saveResponseTimeout = 5 * time.Second
+	// purgeIdempotencyKeysInterval is how often the expired keys are deleted
+	purgeIdempotencyKeysInterval = time.Hour
+)
+
+// withIdempotencyKey runs handle only once per idempotency key. Repeated requests with the same key and body
+// get the stored response of the first request, a reused key with a different body is rejected.
+// timeout is how long handle can take. A key still in progress after that, and the time to store the response,
+// belongs to a request that died before finishing, so it's taken over instead of blocking retries forever.
+func (a *API) withIdempotencyKey(ctx context.Context, w http.ResponseWriter, key string, body []byte, timeout time.Duration, handle func(w http.ResponseWriter)) {
+	if len(key) > maxIdempotencyKeyLen {
+		a.writeBadRequest(w, CodeInvalidRequest, "Idempotency-Key can't be longer than 255 characters")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	requestHash := hex.EncodeToString(sum[:])
+	// without a timeout there is no telling how long a request can run, the key is only freed when it expires
+	var abandonAfter time.Duration
+	if timeout > 0 {
+		abandonAfter = timeout + saveResponseTimeout
+	}
+	stored, reserved, err := a.db.ReserveIdempotencyKey(ctx, key, requestHash, abandonAfter, a.cfg.IdempotencyKeyTTL)
+	if err != nil {
+		a.log.Error(err)
+		a.internalServerError(w)
+		return
+	}
+
+	if !reserved {
+		if stored.RequestHash != requestHash {
+			a.writeUnprocessableEntity(w, CodeIdempotencyKeyReused, "Idempotency-Key is already used for a different request")
+			return
+		}
+		if stored.Response == nil {
+			a.writeConflict(w, CodeIdempotencyKeyInProgress, "request with this Idempotency-Key is still in progress")
+			return
+		}
+
+		for name, value := range stored.Response.Headers {
+			w.Header().Set(name, value)
+		}
+		w.WriteHeader(stored.Response.Status)
+		a.writeResponse(w, stored.Response.Body)
+		return
+	}
+
+	rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	handle(rec)
+
+	// the request context may be already expired here, but the key must not stay in progress forever
+	saveCtx, cancel := context.WithTimeout(context.Background(), saveResponseTimeout)
+	defer cancel()
+
+	// server errors aren't stored, so the client can retry with the same key
+	if rec.status >= http.StatusInternalServerError {
+		if err = a.db.DeleteIdempotencyKey(saveCtx, key); err != nil {
+			a.log.Error(err)
+		}
+		return
+	}
+
+	headers := make(map[string]string, len(rec.Header()))
+	for name := range rec.Header() {
+		headers[name] = rec.Header().Get(name)
+	}
+	err = a.db.SaveIdempotentResponse(saveCtx, key, db.IdempotentResponse{
+		Status:  rec.status,
+		Headers: headers,
+		Body:    rec.body.Bytes(),
+	})
+	if err != nil {
+		a.log.Error(err)
+	}
+}
+
+// PurgeIdempotencyKeys deletes the expired idempotency keys every hour until ctx is done.
+// It returns right away when the keys are kept forever.
+func (a *API) PurgeIdempotencyKeys(ctx context.Context) {
+	if a.cfg.IdempotencyKeyTTL == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(purgeIdempotencyKeysInterval)
+	defer ticker.Stop()
+	for {
+		deleted, err := a.db.DeleteExpiredIdempotencyKeys(ctx, a.cfg.IdempotencyKeyTTL)
+		if err != nil && ctx.Err() == nil {
+			a.log.Error(err)
+		} else if deleted > 0 {
+			a.log.Infof("deleted %d expired idempotency keys", deleted)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// responseRecorder passes the response through to the client and keeps a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"space-trouble-bookings-api/db"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type LaunchpadCapacitiesResponse struct {
+	// DefaultSeats applies to the launchpads not listed in Capacities. 0 means unlimited.
+	DefaultSeats int                 `json:"default_seats"`
+	Capacities   []LaunchpadCapacity `json:"capacities"`
+}
+
+type LaunchpadCapacity struct {
+	LaunchpadID string `json:"launchpad_id"`
+	Seats       int    `json:"seats"`
+}
+
+type LaunchpadCapacityRequest struct {
+	Seats int `json:"seats"`
+}
+
+// LaunchpadCapacities lists the seats on the flights from the launchpads having their own capacity.
+func (a *API) LaunchpadCapacities(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	capacities, err := a.db.LaunchpadCapacities(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	respCapacities := make([]LaunchpadCapacity, 0, len(capacities))
+	for launchpadID, seats := range capacities {
+		respCapacities = append(respCapacities, LaunchpadCapacity{LaunchpadID: launchpadID, Seats: seats})
+	}
+	sort.Slice(respCapacities, func(i, j int) bool {
+		return respCapacities[i].LaunchpadID < respCapacities[j].LaunchpadID
+	})
+
+	a.writeJSON(w, http.StatusOK, LaunchpadCapacitiesResponse{DefaultSeats: a.cfg.DefaultLaunchCapacity, Capacities: respCapacities})
+}
+
+// SetLaunchpadCapacity sets the seats on the flights from the launchpad. Bookings made already are kept
+// even when there are more of them than the new capacity.
+func (a *API) SetLaunchpadCapacity(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	b, ok := a.readBody(w, r)
+	if !ok {
+		return
+	}
+	req := LaunchpadCapacityRequest{}
+	if !a.decodeJSON(w, b, &req) {
+		return
+	}
+	if req.Seats < 1 {
+		a.writeBadRequest(w, CodeInvalidRequest, "seats should be an integer and >0")
+		return
+	}
+
+	launchpadID := chi.URLParam(r, "launchpad_id")
+	if err := a.db.SetLaunchpadCapacity(ctx, launchpadID, req.Seats); err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, LaunchpadCapacity{LaunchpadID: launchpadID, Seats: req.Seats})
+}
+
+// DeleteLaunchpadCapacity makes the launchpad use the default capacity again.
+func (a *API) DeleteLaunchpadCapacity(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	err := a.db.DeleteLaunchpadCapacity(ctx, chi.URLParam(r, "launchpad_id"))
+	if err != nil {
+		if err == db.ErrNotFound {
+			a.writeNotFound(w, CodeLaunchpadCapacityNotFound, "launchpad capacity doesn't exist")
+			return
+		}
+		a.writeServerError(w, err)
+		return
+	}
+
+	a.writeNoContent(w)
+}
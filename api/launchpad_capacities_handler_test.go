@@ -0,0 +1,143 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func TestAPI_LaunchpadCapacities(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "list capacities",
+			method:         "GET",
+			path:           "/admin/launchpad-capacities",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"default_seats":100,"capacities":[{"launchpad_id":"a","seats":10},{"launchpad_id":"b","seats":20}]}`,
+		},
+		{
+			name:           "set capacity",
+			method:         "PUT",
+			path:           "/admin/launchpad-capacities/c",
+			body:           `{"seats": 30}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"launchpad_id":"c","seats":30}`,
+		},
+		{
+			name:           "set zero capacity",
+			method:         "PUT",
+			path:           "/admin/launchpad-capacities/c",
+			body:           `{"seats": 0}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"seats should be an integer and \u003e0","code":"invalid_request"}`,
+		},
+		{
+			name:           "delete capacity",
+			method:         "DELETE",
+			path:           "/admin/launchpad-capacities/a",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "delete nonexistent capacity",
+			method:         "DELETE",
+			path:           "/admin/launchpad-capacities/c",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"/problems/launchpad_capacity_not_found","title":"Launchpad capacity not found","status":404,"detail":"launchpad capacity doesn't exist","code":"launchpad_capacity_not_found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			log: zap.NewNop().Sugar(),
+			db:  &dbMock{capacities: map[string]int{"b": 20, "a": 10}},
+			cfg: Config{DefaultLaunchCapacity: 100},
+			now: testNow,
+		}
+		r := chi.NewRouter()
+		r.Get("/admin/launchpad-capacities", a.LaunchpadCapacities)
+		r.Put("/admin/launchpad-capacities/{launchpad_id}", a.SetLaunchpadCapacity)
+		r.Delete("/admin/launchpad-capacities/{launchpad_id}", a.DeleteLaunchpadCapacity)
+
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
+
+func TestAPI_BookFlight_Capacity(t *testing.T) {
+	testCases := []struct {
+		name           string
+		capacities     map[string]int
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "seats left with the default capacity",
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":2,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"a","destination_id":3,"launch_date":"2022-10-08","status":"confirmed"}`,
+		},
+		{
+			name:           "flight is full",
+			capacities:     map[string]int{"a": 1},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/flight_full","title":"Flight is full","status":409,"detail":"Flight can't be booked: The flight from launchpad a on 2022-10-08 is full","code":"flight_full"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			spacex: &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}},
+			log:    zap.NewNop().Sugar(),
+			db: &dbMock{
+				destinations: testDestinations,
+				bookings: []db.Booking{
+					{ID: 1, LaunchpadID: "a", DestinationID: 3, LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)},
+				},
+				capacities: tc.capacities,
+			},
+			scheduler: schedule.Rotation{},
+			cfg:       Config{DefaultLaunchCapacity: 2},
+			now:       testNow,
+		}
+
+		body := `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "a"}`
+		resp := httptest.NewRecorder()
+		a.BookFlight(resp, httptest.NewRequest("POST", "/booking", strings.NewReader(body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
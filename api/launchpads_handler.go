@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// launchpadStatusActive is the status SpaceX reports for launchpads in use
+const launchpadStatusActive = "active"
+
+type LaunchpadsResponse struct {
+	Launchpads []Launchpad `json:"launchpads"`
+}
+
+type Launchpad struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	FullName  string  `json:"full_name"`
+	Locality  string  `json:"locality"`
+	Region    string  `json:"region"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timezone  string  `json:"timezone"`
+	Status    string  `json:"status"`
+}
+
+func (a *API) Launchpads(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.LaunchpadsTimeout)
+	defer cancel()
+
+	var activeOnly bool
+	q := r.URL.Query()
+	if q.Has("active_only") {
+		var err error
+		activeOnly, err = strconv.ParseBool(q.Get("active_only"))
+		if err != nil {
+			a.writeBadRequest(w, CodeInvalidRequest, "active_only should be true or false")
+			return
+		}
+	}
+
+	launchpads, err := a.spacex.GetAllLaunchpads(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	respLaunchpads := make([]Launchpad, 0, len(launchpads))
+	for _, launchpad := range launchpads {
+		if activeOnly && launchpad.Status != launchpadStatusActive {
+			continue
+		}
+		respLaunchpads = append(respLaunchpads, Launchpad{
+			ID:        launchpad.ID,
+			Name:      launchpad.Name,
+			FullName:  launchpad.FullName,
+			Locality:  launchpad.Locality,
+			Region:    launchpad.Region,
+			Latitude:  launchpad.Latitude,
+			Longitude: launchpad.Longitude,
+			Timezone:  launchpad.Timezone,
+			Status:    launchpad.Status,
+		})
+	}
+	// same order as the launchpads take in the rotation
+	sort.Slice(respLaunchpads, func(i, j int) bool {
+		return respLaunchpads[i].ID < respLaunchpads[j].ID
+	})
+
+	a.writeJSON(w, http.StatusOK, LaunchpadsResponse{Launchpads: respLaunchpads})
+}
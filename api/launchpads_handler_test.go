@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"space-trouble-bookings-api/spacex"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_Launchpads(t *testing.T) {
+	launchpads := []spacex.Launchpad{
+		{
+			ID:        "5e9e4502f509094188566f88",
+			Name:      "KSC LC 39A",
+			FullName:  "Kennedy Space Center Historic Launch Complex 39A",
+			Locality:  "Cape Canaveral",
+			Region:    "Florida",
+			Latitude:  28.6080585,
+			Longitude: -80.6039558,
+			Timezone:  "America/New_York",
+			Status:    "active",
+		},
+		{
+			ID:        "5e9e4501f5090910d4566f83",
+			Name:      "VAFB SLC 3W",
+			FullName:  "Vandenberg Space Force Base Space Launch Complex 3W",
+			Locality:  "Vandenberg Space Force Base",
+			Region:    "California",
+			Latitude:  34.6440904,
+			Longitude: -120.5931438,
+			Timezone:  "America/Los_Angeles",
+			Status:    "retired",
+		},
+	}
+	testCases := []struct {
+		name           string
+		queryParams    url.Values
+		spacexErr      error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "all launchpads",
+			queryParams:    url.Values{},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"launchpads":[` +
+				`{"id":"5e9e4501f5090910d4566f83","name":"VAFB SLC 3W","full_name":"Vandenberg Space Force Base Space Launch Complex 3W","locality":"Vandenberg Space Force Base","region":"California","latitude":34.6440904,"longitude":-120.5931438,"timezone":"America/Los_Angeles","status":"retired"},` +
+				`{"id":"5e9e4502f509094188566f88","name":"KSC LC 39A","full_name":"Kennedy Space Center Historic Launch Complex 39A","locality":"Cape Canaveral","region":"Florida","latitude":28.6080585,"longitude":-80.6039558,"timezone":"America/New_York","status":"active"}]}`,
+		},
+		{
+			name:           "active launchpads only",
+			queryParams:    url.Values{"active_only": []string{"true"}},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"launchpads":[` +
+				`{"id":"5e9e4502f509094188566f88","name":"KSC LC 39A","full_name":"Kennedy Space Center Historic Launch Complex 39A","locality":"Cape Canaveral","region":"Florida","latitude":28.6080585,"longitude":-80.6039558,"timezone":"America/New_York","status":"active"}]}`,
+		},
+		{
+			name:           "invalid active_only",
+			queryParams:    url.Values{"active_only": []string{"yes please"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"active_only should be true or false","code":"invalid_request"}`,
+		},
+		{
+			name:           "spacex is unavailable",
+			queryParams:    url.Values{},
+			spacexErr:      &spacex.UnavailableError{RetryAfter: time.Second, Err: errors.New("timeout")},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"type":"/problems/spacex_unavailable","title":"SpaceX API is unavailable","status":503,"detail":"SpaceX API is unavailable at the moment, try again later","code":"spacex_unavailable"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			spacex: &spacexMock{launchpads: launchpads, err: tc.spacexErr},
+			log:    zap.NewNop().Sugar(),
+		}
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/launchpads", nil)
+		req.URL.RawQuery = tc.queryParams.Encode()
+		a.Launchpads(resp, req)
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// NotFound answers the requests to paths without a route, so they get a problem like any other error.
+func (a *API) NotFound(w http.ResponseWriter, r *http.Request) {
+	a.writeNotFound(w, CodeRouteNotFound, fmt.Sprintf("%s doesn't exist", r.URL.Path))
+}
+
+// MethodNotAllowed answers the requests to existing paths with a method they don't support.
+func (a *API) MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	a.writeProblem(w, newProblem(http.StatusMethodNotAllowed, CodeMethodNotAllowed, fmt.Sprintf("%s isn't supported on %s", r.Method, r.URL.Path)))
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func TestAPI_NotFound(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "unknown path",
+			method:         "GET",
+			path:           "/bookings",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"/problems/route_not_found","title":"Route not found","status":404,"detail":"/bookings doesn't exist","code":"route_not_found"}`,
+		},
+		{
+			name:           "unsupported method",
+			method:         "PUT",
+			path:           "/booking/1",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   `{"type":"/problems/method_not_allowed","title":"Method not allowed","status":405,"detail":"PUT isn't supported on /booking/1","code":"method_not_allowed"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{log: zap.NewNop().Sugar()}
+		r := chi.NewRouter()
+		r.NotFound(a.NotFound)
+		r.MethodNotAllowed(a.MethodNotAllowed)
+		r.Get("/booking/{id}", a.Booking)
+
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, nil))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"net/http"
+)
+
+const (
+	jsonContentType    = "application/json"
+	problemContentType = "application/problem+json"
+)
+
+// ErrorCode identifies the kind of problem, of the whole request or of one of its fields. Codes are part
+// of the API, clients match on them instead of the detail, so existing codes must never change.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest    ErrorCode = "invalid_request"
+	CodeValidationFailed  ErrorCode = "validation_failed"
+	CodeInternalError     ErrorCode = "internal_error"
+	CodeSpaceXUnavailable ErrorCode = "spacex_unavailable"
+	CodeRouteNotFound     ErrorCode = "route_not_found"
+	CodeMethodNotAllowed  ErrorCode = "method_not_allowed"
+	CodeBodyTooLarge      ErrorCode = "body_too_large"
+
+	CodeBookingNotFound          ErrorCode = "booking_not_found"
+	CodeBookingNotConfirmed      ErrorCode = "booking_not_confirmed"
+	CodeBookingChanged           ErrorCode = "booking_changed"
+	CodeSameFlight               ErrorCode = "same_flight"
+	CodeLaunchInPast             ErrorCode = "launch_in_past"
+	CodeFlightAvailable          ErrorCode = "flight_available"
+	CodeIdempotencyKeyReused     ErrorCode = "idempotency_key_reused"
+	CodeIdempotencyKeyInProgress ErrorCode = "idempotency_key_in_progress"
+
+	CodeDestinationNotFound     ErrorCode = "destination_not_found"
+	CodeDestinationExists       ErrorCode = "destination_exists"
+	CodeDestinationInUse        ErrorCode = "destination_in_use"
+	CodeNoDestinations          ErrorCode = "no_destinations"
+	CodeLaunchpadNotFound       ErrorCode = "launchpad_not_found"
+	CodeLaunchpadBusy           ErrorCode = "launchpad_busy"
+	CodeLaunchpadClosed         ErrorCode = "launchpad_closed"
+	CodeDestinationLocked       ErrorCode = "destination_locked"
+	CodeDestinationNotScheduled ErrorCode = "destination_not_scheduled"
+	CodeFlightFull              ErrorCode = "flight_full"
+
+	CodeTimetableEntryNotFound    ErrorCode = "timetable_entry_not_found"
+	CodeScheduleOverrideNotFound  ErrorCode = "schedule_override_not_found"
+	CodeLaunchpadCapacityNotFound ErrorCode = "launchpad_capacity_not_found"
+
+	// the codes of the fields listed in the errors of a validation_failed problem
+	CodeRequired      ErrorCode = "required"
+	CodeInvalidFormat ErrorCode = "invalid_format"
+	CodeInvalidValue  ErrorCode = "invalid_value"
+	CodeOutOfRange    ErrorCode = "out_of_range"
+	CodeInPast        ErrorCode = "in_past"
+	CodeInFuture      ErrorCode = "in_future"
+	CodeUnknownField  ErrorCode = "unknown_field"
+)
+
+// errorTitles is the catalogue of the problems. The title sums up the kind of problem and stays the same
+// for every occurrence, the detail of the Problem explains the occurrence.
+var errorTitles = map[ErrorCode]string{
+	CodeInvalidRequest:    "Invalid request",
+	CodeValidationFailed:  "Invalid request fields",
+	CodeInternalError:     "Internal Server Error",
+	CodeSpaceXUnavailable: "SpaceX API is unavailable",
+	CodeRouteNotFound:     "Route not found",
+	CodeMethodNotAllowed:  "Method not allowed",
+	CodeBodyTooLarge:      "Request body too large",
+
+	CodeBookingNotFound:          "Booking not found",
+	CodeBookingNotConfirmed:      "Booking isn't confirmed",
+	CodeBookingChanged:           "Booking changed meanwhile",
+	CodeSameFlight:               "Booking is on that flight already",
+	CodeLaunchInPast:             "Launch date is in the past",
+	CodeFlightAvailable:          "Flight can be booked",
+	CodeIdempotencyKeyReused:     "Idempotency key reused",
+	CodeIdempotencyKeyInProgress: "Idempotent request in progress",
+
+	CodeDestinationNotFound:     "Destination not found",
+	CodeDestinationExists:       "Destination already exists",
+	CodeDestinationInUse:        "Destination is in use",
+	CodeNoDestinations:          "No destinations available",
+	CodeLaunchpadNotFound:       "Launchpad not found",
+	CodeLaunchpadBusy:           "Launchpad is used by SpaceX",
+	CodeLaunchpadClosed:         "Launchpad is closed",
+	CodeDestinationLocked:       "Day is locked to another destination",
+	CodeDestinationNotScheduled: "Destination isn't scheduled",
+	CodeFlightFull:              "Flight is full",
+
+	CodeTimetableEntryNotFound:    "Timetable entry not found",
+	CodeScheduleOverrideNotFound:  "Schedule override not found",
+	CodeLaunchpadCapacityNotFound: "Launchpad capacity not found",
+
+	CodeRequired:      "Field is required",
+	CodeInvalidFormat: "Field has an invalid format",
+	CodeInvalidValue:  "Field has an invalid value",
+	CodeOutOfRange:    "Field is out of range",
+	CodeInPast:        "Date is in the past",
+	CodeInFuture:      "Date is in the future",
+	CodeUnknownField:  "Unknown field",
+}
+
+// Problem is an RFC 7807 error response.
+type Problem struct {
+	Type   string    `json:"type"`
+	Title  string    `json:"title"`
+	Status int       `json:"status"`
+	Detail string    `json:"detail"`
+	Code   ErrorCode `json:"code"`
+	// Errors lists the invalid fields when the request doesn't pass validation
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+func newProblem(status int, code ErrorCode, detail string) Problem {
+	return Problem{
+		Type:   "/problems/" + string(code),
+		Title:  errorTitles[code],
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (a *API) writeProblem(w http.ResponseWriter, problem Problem) {
+	a.write(w, problem.Status, problemContentType, problem)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// readBody reads the whole request body, up to MaxBodyBytes. A zero MaxBodyBytes leaves the body unlimited.
+// When the body can't be read it writes the error response and returns false.
+func (a *API) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body := r.Body
+	if a.cfg.MaxBodyBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, a.cfg.MaxBodyBytes)
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			a.writeProblem(w, newProblem(http.StatusRequestEntityTooLarge, CodeBodyTooLarge,
+				fmt.Sprintf("request body can't be longer than %d bytes", tooLarge.Limit)))
+			return nil, false
+		}
+
+		a.log.Error(err)
+		a.internalServerError(w)
+		return nil, false
+	}
+	return b, true
+}
+
+// decodeJSON decodes the body into v strictly: unknown fields and anything after the JSON value are rejected,
+// so a typo in a field name doesn't pass silently as a missing field. When the body is invalid it writes
+// the error response and returns false.
+func (a *API) decodeJSON(w http.ResponseWriter, b []byte, v interface{}) bool {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if field, ok := unknownField(err); ok {
+			fv := &validator{}
+			fv.add(field, CodeUnknownField, fmt.Sprintf("unknown field %s", field))
+			a.writeValidationError(w, fv)
+			return false
+		}
+
+		a.writeBadRequest(w, CodeInvalidRequest, err.Error())
+		return false
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		a.writeBadRequest(w, CodeInvalidRequest, "request body should contain a single JSON value")
+		return false
+	}
+	return true
+}
+
+// unknownField returns the field name from the error the decoder returns for a field the type doesn't have.
+// encoding/json has no error type for it, so the message is the only way to tell.
+func unknownField(err error) (string, bool) {
+	const prefix = "json: unknown field "
+	if !strings.HasPrefix(err.Error(), prefix) {
+		return "", false
+	}
+	field, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), prefix))
+	if unquoteErr != nil {
+		return "", false
+	}
+	return field, true
+}
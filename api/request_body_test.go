@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/spacex"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_RequestBody(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "body too large",
+			body:           `{"launch_date": "2022-10-03", "birthday": "1993-04-18", "first_name": "` + strings.Repeat("a", 256) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedBody:   `{"type":"/problems/body_too_large","title":"Request body too large","status":413,"detail":"request body can't be longer than 256 bytes","code":"body_too_large"}`,
+		},
+		{
+			name:           "unknown field",
+			body:           `{"launch_date": "2022-10-03", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destinationId": 3, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"unknown field destinationId","code":"validation_failed",` +
+				`"errors":[{"field":"destinationId","code":"unknown_field","detail":"unknown field destinationId"}]}`,
+		},
+		{
+			name:           "missing destination",
+			body:           `{"launch_date": "2022-10-03", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "launchpad_id": "a"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody: `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"field destination_id can't be empty","code":"validation_failed",` +
+				`"errors":[{"field":"destination_id","code":"required","detail":"field destination_id can't be empty"}]}`,
+		},
+		{
+			name:           "second JSON value",
+			body:           `{"launch_date": "2022-10-03"} {"launch_date": "2022-10-04"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"request body should contain a single JSON value","code":"invalid_request"}`,
+		},
+		{
+			name:           "trailing garbage",
+			body:           `{"launch_date": "2022-10-03"}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"request body should contain a single JSON value","code":"invalid_request"}`,
+		},
+		{
+			// the body is decoded and validated, it fails on the schedule checks only
+			name:           "valid body",
+			body:           "{\"launch_date\": \"2022-10-03\", \"birthday\": \"1993-04-18\", \"first_name\": \"fname\", \"last_name\": \"lname\", \"gender\": \"male\", \"destination_id\": 9, \"launchpad_id\": \"a\"}\n",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/destination_not_found","title":"Destination not found","status":422,"detail":"Flight can't be booked: Destination with ID 9 not found","code":"destination_not_found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			spacex: &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}}},
+			log:    zap.NewNop().Sugar(),
+			db:     &dbMock{destinations: testDestinations},
+			cfg:    Config{MaxBodyBytes: 256},
+			now:    testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		a.BookFlight(resp, httptest.NewRequest("POST", "/booking", strings.NewReader(tc.body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
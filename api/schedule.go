@@ -0,0 +1,125 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/spacex"
+)
+
+func sortedLaunchpadIDs(launchpads []spacex.Launchpad) []string {
+	ids := make([]string, 0, len(launchpads))
+	for _, launchpad := range launchpads {
+		ids = append(ids, launchpad.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sortedDestinationIDs(destinations map[int]string) []int {
+	ids := make([]int, 0, len(destinations))
+	for id := range destinations {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// closedLaunchpads returns the launchpads ops closed with the overrides.
+func closedLaunchpads(overrides []db.ScheduleOverride) map[string]bool {
+	closed := make(map[string]bool)
+	for _, override := range overrides {
+		if override.Closed {
+			closed[override.LaunchpadID] = true
+		}
+	}
+	return closed
+}
+
+// applyOverrides returns the day's assignment with the destinations forced by the overrides and without the closed launchpads.
+// Overrides to destinations that don't exist anymore are ignored.
+func applyOverrides(assignment map[string]int, overrides []db.ScheduleOverride, destinations map[int]string) map[string]int {
+	overridden := make(map[string]int, len(assignment))
+	for launchpadID, destinationID := range assignment {
+		overridden[launchpadID] = destinationID
+	}
+	for _, override := range overrides {
+		if override.Closed {
+			delete(overridden, override.LaunchpadID)
+			continue
+		}
+		if override.DestinationID == nil {
+			continue
+		}
+		if _, found := destinations[*override.DestinationID]; found {
+			overridden[override.LaunchpadID] = *override.DestinationID
+		}
+	}
+	return overridden
+}
+
+// overridesByDay groups the overrides by day.
+func overridesByDay(overrides []db.ScheduleOverride) map[string][]db.ScheduleOverride {
+	byDay := make(map[string][]db.ScheduleOverride)
+	for _, override := range overrides {
+		date := override.LaunchDate.Format(dateFormat)
+		byDay[date] = append(byDay[date], override)
+	}
+	return byDay
+}
+
+// launchBlocked checks whether anything keeps the destination from being booked on the launchpad that day,
+// regardless of the rotation. firstLaunch is the launch of the first booking made for the day, if there is one.
+func launchBlocked(launchpadID string, destinationID int, closed bool, spacexBusy bool, firstLaunch *db.BookedLaunch) error {
+	if closed {
+		return ScheduleError{Code: CodeLaunchpadClosed, Reason: "The launchpad is closed on that day"}
+	}
+	if spacexBusy {
+		return ScheduleError{Code: CodeLaunchpadBusy, Reason: "SpaceX uses the launchpad on that day"}
+	}
+	if firstLaunch != nil && firstLaunch.DestinationID != destinationID && firstLaunch.LaunchpadID != launchpadID {
+		return ScheduleError{Code: CodeDestinationLocked, Reason: fmt.Sprintf("On that day bookings only for destination %d are allowed", firstLaunch.DestinationID)}
+	}
+	return nil
+}
+
+// launchScheduled reports whether the destination is scheduled on the launchpad that day.
+func launchScheduled(launchpadID string, destinationID int, scheduledDestinationID int, firstLaunch *db.BookedLaunch) bool {
+	// if the launchpad's destination matches the client's requested booking destination
+	if scheduledDestinationID == destinationID {
+		return true
+	}
+
+	// or if there is a different destination on that day (overridden or the schedule shifted because of added/removed launchpads or destinations)
+	// and user requested it
+	return firstLaunch != nil && firstLaunch.DestinationID == destinationID && firstLaunch.LaunchpadID == launchpadID
+}
+
+// launchCapacity returns the seats on the flights from the launchpad, 0 meaning unlimited.
+func (a *API) launchCapacity(capacities map[string]int, launchpadID string) int {
+	if seats, ok := capacities[launchpadID]; ok {
+		return seats
+	}
+	return a.cfg.DefaultLaunchCapacity
+}
+
+// remainingSeats returns the seats left on a flight, or nil when the capacity is unlimited.
+func remainingSeats(capacity, passengers int) *int {
+	if capacity == 0 {
+		return nil
+	}
+	remaining := capacity - passengers
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &remaining
+}
+
+// passengersByLaunchpadDay sums up the passengers flying from each launchpad, by day.
+func passengersByLaunchpadDay(bookedLaunches []db.BookedLaunch) map[launchpadDay]int {
+	passengers := make(map[launchpadDay]int, len(bookedLaunches))
+	for _, launch := range bookedLaunches {
+		passengers[launchpadDay{date: launch.LaunchDate.Format(dateFormat), launchpadID: launch.LaunchpadID}] += launch.Passengers
+	}
+	return passengers
+}
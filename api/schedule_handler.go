@@ -0,0 +1,193 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/spacex"
+	"time"
+)
+
+const (
+	// maxScheduleDays caps the number of days returned by one schedule request
+	maxScheduleDays = 31
+	// defaultScheduleDays is the number of days returned when "to" isn't set
+	defaultScheduleDays = 7
+)
+
+type ScheduleResponse struct {
+	Days []ScheduleDay `json:"days"`
+}
+
+type ScheduleDay struct {
+	Date string `json:"date"`
+	// LockedDestinationID is set when there are bookings on that day already. Only that destination can be booked then,
+	// even if it differs from the rotation.
+	LockedDestinationID *int                 `json:"locked_destination_id"`
+	Launchpads          []ScheduledLaunchpad `json:"launchpads"`
+}
+
+type ScheduledLaunchpad struct {
+	LaunchpadID string `json:"launchpad_id"`
+	// DestinationID is empty when the launchpad doesn't fly on that day
+	DestinationID *int `json:"destination_id"`
+	// SpaceXBlocked is set when SpaceX uses the launchpad on that day
+	SpaceXBlocked bool `json:"spacex_blocked"`
+	// Closed is set when ops closed the launchpad on that day
+	Closed bool `json:"closed"`
+	// RemainingSeats is empty when the capacity is unlimited
+	RemainingSeats *int `json:"remaining_seats"`
+}
+
+// dateRange reads the from-to range of days from the query. It defaults to a week starting today
+// and can't be longer than maxScheduleDays.
+func (a *API) dateRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	now := a.now()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	q := r.URL.Query()
+	if q.Has("from") {
+		var err error
+		from, err = time.Parse(dateFormat, q.Get("from"))
+		if err != nil {
+			a.writeBadRequest(w, CodeInvalidRequest, "from should be in format YYYY-MM-DD")
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	to := from.AddDate(0, 0, defaultScheduleDays-1)
+	if q.Has("to") {
+		var err error
+		to, err = time.Parse(dateFormat, q.Get("to"))
+		if err != nil {
+			a.writeBadRequest(w, CodeInvalidRequest, "to should be in format YYYY-MM-DD")
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	if to.Before(from) {
+		a.writeBadRequest(w, CodeInvalidRequest, "to can't be before from")
+		return time.Time{}, time.Time{}, false
+	}
+	if to.After(from.AddDate(0, 0, maxScheduleDays-1)) {
+		a.writeBadRequest(w, CodeInvalidRequest, fmt.Sprintf("the range can't be longer than %d days", maxScheduleDays))
+		return time.Time{}, time.Time{}, false
+	}
+
+	return from, to, true
+}
+
+// Schedule returns the destination assigned to every launchpad for every day in the from-to range.
+func (a *API) Schedule(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.ScheduleTimeout)
+	defer cancel()
+
+	from, to, ok := a.dateRange(w, r)
+	if !ok {
+		return
+	}
+
+	launchpads, err := a.spacex.GetAllLaunchpads(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	upcomingLaunches, err := a.spacex.GetUpcomingLaunches(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	destinations, err := a.getDestinationsMap(ctx, a.db)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	bookedLaunches, err := a.db.BookedLaunches(ctx, from, to)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	overrides, err := a.db.ScheduleOverrides(ctx, from, to)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	capacities, err := a.db.LaunchpadCapacities(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	launchpadIDs := sortedLaunchpadIDs(launchpads)
+	destinationIDs := sortedDestinationIDs(destinations)
+	busy := a.spacexBusyLaunchpads(upcomingLaunches)
+	firstLaunches := firstLaunchesByDay(bookedLaunches)
+	dayOverrides := overridesByDay(overrides)
+	passengers := passengersByLaunchpadDay(bookedLaunches)
+
+	assigned, err := a.scheduler.AssignRange(ctx, from, to, launchpadIDs, destinationIDs)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	days := make([]ScheduleDay, 0, len(assigned))
+	for i := range assigned {
+		date := from.AddDate(0, 0, i).Format(dateFormat)
+		launchpadToDestination := applyOverrides(assigned[i], dayOverrides[date], destinations)
+		closed := closedLaunchpads(dayOverrides[date])
+
+		scheduleDay := ScheduleDay{
+			Date:       date,
+			Launchpads: make([]ScheduledLaunchpad, 0, len(launchpadIDs)),
+		}
+		if firstLaunch, ok := firstLaunches[date]; ok {
+			scheduleDay.LockedDestinationID = &firstLaunch.DestinationID
+		}
+		for _, id := range launchpadIDs {
+			key := launchpadDay{date: date, launchpadID: id}
+			scheduled := ScheduledLaunchpad{
+				LaunchpadID:    id,
+				SpaceXBlocked:  busy[key],
+				Closed:         closed[id],
+				RemainingSeats: remainingSeats(a.launchCapacity(capacities, id), passengers[key]),
+			}
+			if destinationID, ok := launchpadToDestination[id]; ok {
+				scheduled.DestinationID = &destinationID
+			}
+			scheduleDay.Launchpads = append(scheduleDay.Launchpads, scheduled)
+		}
+		days = append(days, scheduleDay)
+	}
+
+	a.writeJSON(w, http.StatusOK, ScheduleResponse{Days: days})
+}
+
+type launchpadDay struct {
+	date        string
+	launchpadID string
+}
+
+// spacexBusyLaunchpads returns the launchpads SpaceX uses, by day.
+func (a *API) spacexBusyLaunchpads(upcomingLaunches []spacex.Launch) map[launchpadDay]bool {
+	busy := make(map[launchpadDay]bool, len(upcomingLaunches))
+	for _, upcomingLaunch := range upcomingLaunches {
+		t, err := time.Parse(time.RFC3339, upcomingLaunch.DateUTC)
+		if err != nil {
+			a.log.Errorf("failed to parse upcoming launch time: %s", err.Error())
+			continue
+		}
+		busy[launchpadDay{date: t.Format(dateFormat), launchpadID: upcomingLaunch.Launchpad}] = true
+	}
+	return busy
+}
+
+// firstLaunchesByDay returns the launch of the first booking made for every day.
+func firstLaunchesByDay(bookedLaunches []db.BookedLaunch) map[string]*db.BookedLaunch {
+	first := make(map[string]*db.BookedLaunch)
+	for i, launch := range bookedLaunches {
+		date := launch.LaunchDate.Format(dateFormat)
+		if _, ok := first[date]; !ok {
+			first[date] = &bookedLaunches[i]
+		}
+	}
+	return first
+}
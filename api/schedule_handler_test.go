@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_Schedule(t *testing.T) {
+	testCases := []struct {
+		name           string
+		queryParams    url.Values
+		overrides      []db.ScheduleOverride
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid from",
+			queryParams:    url.Values{"from": []string{"tomorrow"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"from should be in format YYYY-MM-DD","code":"invalid_request"}`,
+		},
+		{
+			name:           "to before from",
+			queryParams:    url.Values{"from": []string{"2022-10-08"}, "to": []string{"2022-10-07"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"to can't be before from","code":"invalid_request"}`,
+		},
+		{
+			name:           "too long range",
+			queryParams:    url.Values{"from": []string{"2022-10-01"}, "to": []string{"2022-11-01"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"the range can't be longer than 31 days","code":"invalid_request"}`,
+		},
+		{
+			name:           "schedule with blocked and locked days",
+			queryParams:    url.Values{"from": []string{"2022-10-08"}, "to": []string{"2022-10-09"}},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"days":[` +
+				`{"date":"2022-10-08","locked_destination_id":null,"launchpads":[{"launchpad_id":"a","destination_id":3,"spacex_blocked":false,"closed":false,"remaining_seats":3},{"launchpad_id":"b","destination_id":4,"spacex_blocked":true,"closed":false,"remaining_seats":1}]},` +
+				`{"date":"2022-10-09","locked_destination_id":2,"launchpads":[{"launchpad_id":"a","destination_id":4,"spacex_blocked":false,"closed":false,"remaining_seats":2},{"launchpad_id":"b","destination_id":5,"spacex_blocked":false,"closed":false,"remaining_seats":0}]}]}`,
+		},
+		{
+			name:        "schedule with overrides",
+			queryParams: url.Values{"from": []string{"2022-10-08"}, "to": []string{"2022-10-08"}},
+			overrides: []db.ScheduleOverride{
+				{LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", Closed: true},
+				{LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), LaunchpadID: "b", DestinationID: intPtr(7)},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"days":[` +
+				`{"date":"2022-10-08","locked_destination_id":null,"launchpads":[{"launchpad_id":"a","destination_id":null,"spacex_blocked":false,"closed":true,"remaining_seats":3},{"launchpad_id":"b","destination_id":7,"spacex_blocked":true,"closed":false,"remaining_seats":1}]}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			scheduler: schedule.Rotation{},
+			spacex: &spacexMock{
+				launchpads:       []spacex.Launchpad{{ID: "b"}, {ID: "a"}},
+				upcomingLaunches: []spacex.Launch{{Launchpad: "b", DateUTC: "2022-10-08T05:40:00.000Z"}},
+			},
+			log: zap.NewNop().Sugar(),
+			db: &dbMock{
+				destinations: testDestinations,
+				bookings: []db.Booking{
+					{ID: 1, LaunchpadID: "a", DestinationID: 2, LaunchDate: time.Date(2022, 10, 9, 0, 0, 0, 0, time.UTC)},
+					{ID: 2, LaunchpadID: "b", DestinationID: 5, LaunchDate: time.Date(2022, 10, 9, 0, 0, 0, 0, time.UTC)},
+				},
+				overrides:  tc.overrides,
+				capacities: map[string]int{"b": 1},
+			},
+			cfg: Config{DefaultLaunchCapacity: 3},
+			now: testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/schedule", nil)
+		req.URL.RawQuery = tc.queryParams.Encode()
+		a.Schedule(resp, req)
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"space-trouble-bookings-api/db"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ScheduleOverridesResponse struct {
+	Overrides []ScheduleOverride `json:"overrides"`
+}
+
+type ScheduleOverride struct {
+	Date          string `json:"date"`
+	LaunchpadID   string `json:"launchpad_id"`
+	DestinationID *int   `json:"destination_id"`
+	Closed        bool   `json:"closed"`
+}
+
+// ScheduleOverrideRequest either forces the destination on the launchpad or closes it.
+type ScheduleOverrideRequest struct {
+	DestinationID *int `json:"destination_id"`
+	Closed        bool `json:"closed"`
+}
+
+func newScheduleOverride(override db.ScheduleOverride) ScheduleOverride {
+	return ScheduleOverride{
+		Date:          override.LaunchDate.Format(dateFormat),
+		LaunchpadID:   override.LaunchpadID,
+		DestinationID: override.DestinationID,
+		Closed:        override.Closed,
+	}
+}
+
+// ScheduleOverrides lists the overrides ops set on top of the scheduling strategy.
+func (a *API) ScheduleOverrides(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	from, to, ok := a.dateRange(w, r)
+	if !ok {
+		return
+	}
+
+	overrides, err := a.db.ScheduleOverrides(ctx, from, to)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	respOverrides := make([]ScheduleOverride, 0, len(overrides))
+	for _, override := range overrides {
+		respOverrides = append(respOverrides, newScheduleOverride(override))
+	}
+
+	a.writeJSON(w, http.StatusOK, ScheduleOverridesResponse{Overrides: respOverrides})
+}
+
+// SetScheduleOverride forces the destination on the launchpad for the day or closes the launchpad.
+func (a *API) SetScheduleOverride(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	launchDate, err := time.Parse(dateFormat, chi.URLParam(r, "date"))
+	if err != nil {
+		a.writeBadRequest(w, CodeInvalidRequest, "date should be in format YYYY-MM-DD")
+		return
+	}
+
+	b, ok := a.readBody(w, r)
+	if !ok {
+		return
+	}
+	req := ScheduleOverrideRequest{}
+	if !a.decodeJSON(w, b, &req) {
+		return
+	}
+	if (req.DestinationID != nil) == req.Closed {
+		a.writeBadRequest(w, CodeInvalidRequest, "either destination_id or closed should be set")
+		return
+	}
+
+	if req.DestinationID != nil {
+		destinations, err := a.getDestinationsMap(ctx, a.db)
+		if err != nil {
+			a.writeServerError(w, err)
+			return
+		}
+		if _, found := destinations[*req.DestinationID]; !found {
+			a.writeUnprocessableEntity(w, CodeDestinationNotFound, fmt.Sprintf("Destination with ID %d not found", *req.DestinationID))
+			return
+		}
+	}
+
+	override := db.ScheduleOverride{
+		LaunchDate:    launchDate,
+		LaunchpadID:   chi.URLParam(r, "launchpad_id"),
+		DestinationID: req.DestinationID,
+		Closed:        req.Closed,
+	}
+	if err = a.db.SetScheduleOverride(ctx, override); err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, newScheduleOverride(override))
+}
+
+func (a *API) DeleteScheduleOverride(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	launchDate, err := time.Parse(dateFormat, chi.URLParam(r, "date"))
+	if err != nil {
+		a.writeBadRequest(w, CodeInvalidRequest, "date should be in format YYYY-MM-DD")
+		return
+	}
+
+	err = a.db.DeleteScheduleOverride(ctx, launchDate, chi.URLParam(r, "launchpad_id"))
+	if err != nil {
+		if err == db.ErrNotFound {
+			a.writeNotFound(w, CodeScheduleOverrideNotFound, "schedule override doesn't exist")
+			return
+		}
+		a.writeServerError(w, err)
+		return
+	}
+
+	a.writeNoContent(w)
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestAPI_ScheduleOverrides(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "list overrides",
+			method:         "GET",
+			path:           "/admin/schedule-overrides?from=2022-10-08&to=2022-10-09",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"overrides":[{"date":"2022-10-08","launchpad_id":"a","destination_id":null,"closed":true}]}`,
+		},
+		{
+			name:           "force a destination",
+			method:         "PUT",
+			path:           "/admin/schedule-overrides/2022-10-09/b",
+			body:           `{"destination_id": 3}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"date":"2022-10-09","launchpad_id":"b","destination_id":3,"closed":false}`,
+		},
+		{
+			name:           "close a launchpad",
+			method:         "PUT",
+			path:           "/admin/schedule-overrides/2022-10-09/b",
+			body:           `{"closed": true}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"date":"2022-10-09","launchpad_id":"b","destination_id":null,"closed":true}`,
+		},
+		{
+			name:           "both destination and closed",
+			method:         "PUT",
+			path:           "/admin/schedule-overrides/2022-10-09/b",
+			body:           `{"destination_id": 3, "closed": true}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"either destination_id or closed should be set","code":"invalid_request"}`,
+		},
+		{
+			name:           "neither destination nor closed",
+			method:         "PUT",
+			path:           "/admin/schedule-overrides/2022-10-09/b",
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"either destination_id or closed should be set","code":"invalid_request"}`,
+		},
+		{
+			name:           "force a nonexistent destination",
+			method:         "PUT",
+			path:           "/admin/schedule-overrides/2022-10-09/b",
+			body:           `{"destination_id": 9}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/destination_not_found","title":"Destination not found","status":422,"detail":"Destination with ID 9 not found","code":"destination_not_found"}`,
+		},
+		{
+			name:           "delete override",
+			method:         "DELETE",
+			path:           "/admin/schedule-overrides/2022-10-08/a",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "delete nonexistent override",
+			method:         "DELETE",
+			path:           "/admin/schedule-overrides/2022-10-08/b",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"/problems/schedule_override_not_found","title":"Schedule override not found","status":404,"detail":"schedule override doesn't exist","code":"schedule_override_not_found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			log: zap.NewNop().Sugar(),
+			db: &dbMock{
+				destinations: testDestinations,
+				overrides: []db.ScheduleOverride{
+					{LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", Closed: true},
+				},
+			},
+			now: testNow,
+		}
+		r := chi.NewRouter()
+		r.Get("/admin/schedule-overrides", a.ScheduleOverrides)
+		r.Put("/admin/schedule-overrides/{date}/{launchpad_id}", a.SetScheduleOverride)
+		r.Delete("/admin/schedule-overrides/{date}/{launchpad_id}", a.DeleteScheduleOverride)
+
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
+
+func TestAPI_BookFlight_ScheduleOverrides(t *testing.T) {
+	overrides := []db.ScheduleOverride{
+		{LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: intPtr(7)},
+		{LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), LaunchpadID: "b", Closed: true},
+	}
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "forced destination is booked",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 7, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"id":1,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"a","destination_id":7,"launch_date":"2022-10-08","status":"confirmed"}`,
+		},
+		{
+			// destination 3 is on launchpad "a" in the rotation
+			name:           "rotation destination is replaced by the override",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_not_scheduled","title":"Destination isn't scheduled","status":409,"detail":"Flight can't be booked: No launches available for destination 3(Pluto) on launchpad a on 2022-10-08","code":"destination_not_scheduled"}`,
+		},
+		{
+			name:           "closed launchpad",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 4, "launchpad_id": "b"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/launchpad_closed","title":"Launchpad is closed","status":409,"detail":"Flight can't be booked: The launchpad is closed on that day","code":"launchpad_closed"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}},
+			log:       zap.NewNop().Sugar(),
+			db:        &dbMock{destinations: testDestinations, overrides: overrides},
+			scheduler: schedule.Rotation{},
+			now:       testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		a.BookFlight(resp, httptest.NewRequest("POST", "/booking", strings.NewReader(tc.body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"space-trouble-bookings-api/db"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TimetableResponse struct {
+	Entries []TimetableEntry `json:"entries"`
+}
+
+type TimetableEntry struct {
+	Date          string `json:"date"`
+	LaunchpadID   string `json:"launchpad_id"`
+	DestinationID int    `json:"destination_id"`
+}
+
+type TimetableEntryRequest struct {
+	DestinationID int `json:"destination_id"`
+}
+
+// Timetable lists the destinations ops pinned to launchpads for the timetable scheduling strategy.
+func (a *API) Timetable(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	from, to, ok := a.dateRange(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := a.db.TimetableEntries(ctx, from, to)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	respEntries := make([]TimetableEntry, 0, len(entries))
+	for _, entry := range entries {
+		respEntries = append(respEntries, TimetableEntry{
+			Date:          entry.LaunchDate.Format(dateFormat),
+			LaunchpadID:   entry.LaunchpadID,
+			DestinationID: entry.DestinationID,
+		})
+	}
+
+	a.writeJSON(w, http.StatusOK, TimetableResponse{Entries: respEntries})
+}
+
+// SetTimetableEntry pins the destination to the launchpad for the day.
+func (a *API) SetTimetableEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	launchDate, err := time.Parse(dateFormat, chi.URLParam(r, "date"))
+	if err != nil {
+		a.writeBadRequest(w, CodeInvalidRequest, "date should be in format YYYY-MM-DD")
+		return
+	}
+	launchpadID := chi.URLParam(r, "launchpad_id")
+
+	b, ok := a.readBody(w, r)
+	if !ok {
+		return
+	}
+	req := TimetableEntryRequest{}
+	if !a.decodeJSON(w, b, &req) {
+		return
+	}
+
+	destinations, err := a.getDestinationsMap(ctx, a.db)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	if _, found := destinations[req.DestinationID]; !found {
+		a.writeUnprocessableEntity(w, CodeDestinationNotFound, fmt.Sprintf("Destination with ID %d not found", req.DestinationID))
+		return
+	}
+
+	entry := db.TimetableEntry{LaunchDate: launchDate, LaunchpadID: launchpadID, DestinationID: req.DestinationID}
+	if err = a.db.SetTimetableEntry(ctx, entry); err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+
+	a.writeJSON(w, http.StatusOK, TimetableEntry{
+		Date:          launchDate.Format(dateFormat),
+		LaunchpadID:   launchpadID,
+		DestinationID: req.DestinationID,
+	})
+}
+
+func (a *API) DeleteTimetableEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.AdminTimeout)
+	defer cancel()
+
+	launchDate, err := time.Parse(dateFormat, chi.URLParam(r, "date"))
+	if err != nil {
+		a.writeBadRequest(w, CodeInvalidRequest, "date should be in format YYYY-MM-DD")
+		return
+	}
+
+	err = a.db.DeleteTimetableEntry(ctx, launchDate, chi.URLParam(r, "launchpad_id"))
+	if err != nil {
+		if err == db.ErrNotFound {
+			a.writeNotFound(w, CodeTimetableEntryNotFound, "timetable entry doesn't exist")
+			return
+		}
+		a.writeServerError(w, err)
+		return
+	}
+
+	a.writeNoContent(w)
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/db"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func TestAPI_Timetable(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "list entries",
+			method:         "GET",
+			path:           "/admin/timetable?from=2022-10-08&to=2022-10-09",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"entries":[{"date":"2022-10-08","launchpad_id":"a","destination_id":2}]}`,
+		},
+		{
+			name:           "pin a destination",
+			method:         "PUT",
+			path:           "/admin/timetable/2022-10-09/b",
+			body:           `{"destination_id": 3}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"date":"2022-10-09","launchpad_id":"b","destination_id":3}`,
+		},
+		{
+			name:           "pin a nonexistent destination",
+			method:         "PUT",
+			path:           "/admin/timetable/2022-10-09/b",
+			body:           `{"destination_id": 9}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/destination_not_found","title":"Destination not found","status":422,"detail":"Destination with ID 9 not found","code":"destination_not_found"}`,
+		},
+		{
+			name:           "pin with invalid date",
+			method:         "PUT",
+			path:           "/admin/timetable/tomorrow/b",
+			body:           `{"destination_id": 3}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/invalid_request","title":"Invalid request","status":400,"detail":"date should be in format YYYY-MM-DD","code":"invalid_request"}`,
+		},
+		{
+			name:           "unpin",
+			method:         "DELETE",
+			path:           "/admin/timetable/2022-10-08/a",
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:           "unpin nonexistent entry",
+			method:         "DELETE",
+			path:           "/admin/timetable/2022-10-08/b",
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `{"type":"/problems/timetable_entry_not_found","title":"Timetable entry not found","status":404,"detail":"timetable entry doesn't exist","code":"timetable_entry_not_found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		a := &API{
+			log: zap.NewNop().Sugar(),
+			db: &dbMock{
+				destinations: testDestinations,
+				timetable: []db.TimetableEntry{
+					{LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC), LaunchpadID: "a", DestinationID: 2},
+				},
+			},
+			now: testNow,
+		}
+		r := chi.NewRouter()
+		r.Get("/admin/timetable", a.Timetable)
+		r.Put("/admin/timetable/{date}/{launchpad_id}", a.SetTimetableEntry)
+		r.Delete("/admin/timetable/{date}/{launchpad_id}", a.DeleteTimetableEntry)
+
+		resp := httptest.NewRecorder()
+		r.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// FieldError describes a problem with one field of the request: Code is one of the field codes
+// of the catalogue in problems.go, Detail is meant for people.
+type FieldError struct {
+	Field  string    `json:"field"`
+	Code   ErrorCode `json:"code"`
+	Detail string    `json:"detail"`
+}
+
+// validator collects the problems of a request instead of stopping at the first one.
+type validator struct {
+	errors []FieldError
+}
+
+func (v *validator) add(field string, code ErrorCode, detail string) {
+	v.errors = append(v.errors, FieldError{Field: field, Code: code, Detail: detail})
+}
+
+// merge adds the problems of a nested object, like a passenger of a group, prefixing them with its path.
+func (v *validator) merge(path string, nested *validator) {
+	for _, fieldErr := range nested.errors {
+		v.add(path+"."+fieldErr.Field, fieldErr.Code, path+": "+fieldErr.Detail)
+	}
+}
+
+func (v *validator) valid() bool {
+	return len(v.errors) == 0
+}
+
+// message joins the details of all the problems.
+func (v *validator) message() string {
+	details := make([]string, 0, len(v.errors))
+	for _, fieldErr := range v.errors {
+		details = append(details, fieldErr.Detail)
+	}
+	return strings.Join(details, "; ")
+}
+
+// writeValidationError answers 400 with every invalid field. The detail joins their details,
+// so clients reading only the detail still learn what's wrong.
+func (a *API) writeValidationError(w http.ResponseWriter, v *validator) {
+	problem := newProblem(http.StatusBadRequest, CodeValidationFailed, v.message())
+	problem.Errors = v.errors
+	a.writeProblem(w, problem)
+}
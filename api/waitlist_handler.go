@@ -0,0 +1,116 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"space-trouble-bookings-api/db"
+)
+
+// errFlightAvailable is returned when a customer asks to wait for a flight that can be booked right away.
+var errFlightAvailable = errors.New("flight available")
+
+type WaitlistEntry struct {
+	ID            int    `json:"id"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Gender        string `json:"gender"`
+	Birthday      string `json:"birthday"`
+	LaunchpadID   string `json:"launchpad_id"`
+	DestinationID int    `json:"destination_id"`
+	LaunchDate    string `json:"launch_date"`
+	// Reason tells why the flight couldn't be booked
+	Reason string `json:"reason"`
+}
+
+// JoinWaitlist puts the booking on the waitlist of a full flight. The entry gets booked when a booking
+// for the same launchpad, day and destination is deleted.
+func (a *API) JoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := requestContext(r, a.cfg.WaitlistTimeout)
+	defer cancel()
+	b, ok := a.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	flightBooking, requested, ok := a.readBookingRequest(w, b)
+	if !ok {
+		return
+	}
+
+	sx, err := a.getSpaceXData(ctx)
+	if err != nil {
+		a.writeServerError(w, err)
+		return
+	}
+	var launchpadFound bool
+	for _, launchpad := range sx.launchpads {
+		if launchpad.ID == requested.LaunchpadID {
+			launchpadFound = true
+		}
+	}
+	if !launchpadFound {
+		a.writeUnprocessableEntity(w, CodeLaunchpadNotFound, fmt.Sprintf("Requested launchpad with ID %q not found", requested.LaunchpadID))
+		return
+	}
+
+	var entry db.WaitlistEntry
+	var reason string
+	err = a.db.WithLaunchDayLock(ctx, requested.LaunchDate, func(tx db.Storage) error {
+		destinations, err := a.getDestinationsMap(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if _, found := destinations[requested.DestinationID]; !found {
+			return ScheduleError{Code: CodeDestinationNotFound, Reason: fmt.Sprintf("Destination with ID %d not found", requested.DestinationID)}
+		}
+
+		err = a.flightSchedulable(ctx, tx, sx, flightBooking, 1)
+		if err == nil {
+			return errFlightAvailable
+		}
+		// only a freed seat promotes an entry, so waiting makes sense for a full flight only. A flight that
+		// isn't scheduled or whose launchpad is busy or closed wouldn't get one
+		scheduleErr, ok := err.(ScheduleError)
+		if !ok || scheduleErr.Code != CodeFlightFull {
+			return err
+		}
+		reason = scheduleErr.Reason
+
+		entry, err = tx.CreateWaitlistEntry(ctx, db.WaitlistEntry{
+			FirstName:     requested.FirstName,
+			LastName:      requested.LastName,
+			Gender:        requested.Gender,
+			Birthday:      requested.Birthday,
+			LaunchpadID:   requested.LaunchpadID,
+			DestinationID: requested.DestinationID,
+			LaunchDate:    requested.LaunchDate,
+		})
+		return err
+	})
+	if err != nil {
+		if err == errFlightAvailable {
+			a.writeConflict(w, CodeFlightAvailable, "The flight can be booked, there's no need to wait")
+			return
+		}
+		if scheduleErr, ok := err.(ScheduleError); ok {
+			a.writeScheduleError(w, scheduleErr)
+			return
+		}
+
+		a.writeServerError(w, err)
+		return
+	}
+
+	a.writeJSON(w, http.StatusCreated, WaitlistEntry{
+		ID:            entry.ID,
+		FirstName:     entry.FirstName,
+		LastName:      entry.LastName,
+		Gender:        entry.Gender,
+		Birthday:      entry.Birthday.Format(dateFormat),
+		LaunchpadID:   entry.LaunchpadID,
+		DestinationID: entry.DestinationID,
+		LaunchDate:    entry.LaunchDate.Format(dateFormat),
+		Reason:        reason,
+	})
+}
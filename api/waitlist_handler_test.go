@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
+	"space-trouble-bookings-api/spacex"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAPI_JoinWaitlist(t *testing.T) {
+	testCases := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedBody     string
+		expectedWaitlist int
+	}{
+		{
+			name:           "invalid gender",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "none", "destination_id": 3, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"type":"/problems/validation_failed","title":"Invalid request fields","status":400,"detail":"Gender should be male or female","code":"validation_failed","errors":[{"field":"gender","code":"invalid_value","detail":"Gender should be male or female"}]}`,
+		},
+		{
+			name:           "launchpad not found",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "c"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/launchpad_not_found","title":"Launchpad not found","status":422,"detail":"Requested launchpad with ID \"c\" not found","code":"launchpad_not_found"}`,
+		},
+		{
+			name:           "destination not found",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 9, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"type":"/problems/destination_not_found","title":"Destination not found","status":422,"detail":"Flight can't be booked: Destination with ID 9 not found","code":"destination_not_found"}`,
+		},
+		{
+			name:             "full flight",
+			body:             `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 3, "launchpad_id": "a"}`,
+			expectedStatus:   http.StatusCreated,
+			expectedBody:     `{"id":1,"first_name":"fname","last_name":"lname","gender":"male","birthday":"1993-04-18","launchpad_id":"a","destination_id":3,"launch_date":"2022-10-08","reason":"The flight from launchpad a on 2022-10-08 is full"}`,
+			expectedWaitlist: 1,
+		},
+		{
+			name:           "destination isn't scheduled",
+			body:           `{"launch_date": "2022-10-08", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 4, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/destination_not_scheduled","title":"Destination isn't scheduled","status":409,"detail":"Flight can't be booked: No launches available for destination 4(Asteroid Belt) on launchpad a on 2022-10-08","code":"destination_not_scheduled"}`,
+		},
+		{
+			name:           "flight can be booked",
+			body:           `{"launch_date": "2022-10-09", "birthday": "1993-04-18", "first_name": "fname", "last_name": "lname", "gender": "male", "destination_id": 4, "launchpad_id": "a"}`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"type":"/problems/flight_available","title":"Flight can be booked","status":409,"detail":"The flight can be booked, there's no need to wait","code":"flight_available"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		dbm := &dbMock{
+			destinations: testDestinations,
+			bookings: []db.Booking{
+				{ID: 1, LaunchpadID: "a", DestinationID: 3, LaunchDate: time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)},
+			},
+			capacities: map[string]int{"a": 1},
+		}
+		a := &API{
+			spacex:    &spacexMock{launchpads: []spacex.Launchpad{{ID: "a"}, {ID: "b"}}},
+			log:       zap.NewNop().Sugar(),
+			db:        dbm,
+			scheduler: schedule.Rotation{},
+			now:       testNow,
+		}
+
+		resp := httptest.NewRecorder()
+		a.JoinWaitlist(resp, httptest.NewRequest("POST", "/waitlist", strings.NewReader(tc.body)))
+		if tc.expectedStatus != resp.Code {
+			t.Logf("unexpected status code. Got %d, want %d", resp.Code, tc.expectedStatus)
+			t.Fail()
+		}
+		checkContentType(t, resp)
+		if tc.expectedBody != resp.Body.String() {
+			t.Logf("unexpected body. Got %s, want %s", resp.Body.String(), tc.expectedBody)
+			t.Fail()
+		}
+		if len(dbm.waitlist) != tc.expectedWaitlist {
+			t.Logf("unexpected waitlist length. Got %d, want %d", len(dbm.waitlist), tc.expectedWaitlist)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Command spacex-stub serves the subset of the SpaceX API used by the bookings API from fixture files,
+// so the whole stack can run without network access.
+package main
+
+import (
+	"embed"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+//go:embed fixtures/*.json
+var embeddedFixtures embed.FS
+
+func main() {
+	addr := flag.String("addr", ":8081", "address to listen on")
+	fixturesDir := flag.String("fixtures", "", "directory with launchpads.json and upcoming_launches.json, the built-in fixtures are used if empty")
+	flag.Parse()
+
+	zapLog, err := zap.NewProduction()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer func() {
+		err := zapLog.Sync()
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+	l := zapLog.Sugar()
+
+	fixtures, err := fs.Sub(embeddedFixtures, "fixtures")
+	if err != nil {
+		l.Fatal(err)
+	}
+	if *fixturesDir != "" {
+		fixtures = os.DirFS(*fixturesDir)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/v4/launchpads", serveFixture(fixtures, "launchpads.json", l))
+	r.Get("/v5/launches/upcoming", serveFixture(fixtures, "upcoming_launches.json", l))
+
+	l.Infof("Listening on %s", *addr)
+	if err := http.ListenAndServe(*addr, r); err != nil {
+		l.Fatal(err)
+	}
+}
+
+func serveFixture(fixtures fs.FS, name string, l *zap.SugaredLogger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b, err := fs.ReadFile(fixtures, name)
+		if err != nil {
+			l.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(b); err != nil {
+			l.Error(err)
+		}
+	}
+}
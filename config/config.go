@@ -1,7 +1,207 @@
 package config
 
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Config struct {
+	ListenAddr        string        `env:"LISTEN_ADDR" envDefault:":8080"`
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT" envDefault:"5s"`
+	ReadTimeout       time.Duration `env:"READ_TIMEOUT" envDefault:"15s"`
+	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT" envDefault:"30s"`
+	IdleTimeout       time.Duration `env:"IDLE_TIMEOUT" envDefault:"60s"`
+	MaxHeaderBytes    int           `env:"MAX_HEADER_BYTES" envDefault:"1048576"`
+	MaxBodyBytes      int64         `env:"MAX_BODY_BYTES" envDefault:"65536"`
+	// ShutdownGrace is how long in-flight requests can take to finish on shutdown
+	ShutdownGrace time.Duration `env:"SHUTDOWN_GRACE" envDefault:"10s"`
+
+	BookingsTimeout          time.Duration `env:"BOOKINGS_TIMEOUT" envDefault:"10s"`
+	BookingTimeout           time.Duration `env:"BOOKING_TIMEOUT" envDefault:"5s"`
+	BookFlightTimeout        time.Duration `env:"BOOK_FLIGHT_TIMEOUT" envDefault:"10s"`
+	BookingDeleteTimeout     time.Duration `env:"BOOKING_DELETE_TIMEOUT" envDefault:"5s"`
+	BookingRescheduleTimeout time.Duration `env:"BOOKING_RESCHEDULE_TIMEOUT" envDefault:"10s"`
+	DestinationsTimeout      time.Duration `env:"DESTINATIONS_TIMEOUT" envDefault:"5s"`
+	LaunchpadsTimeout        time.Duration `env:"LAUNCHPADS_TIMEOUT" envDefault:"10s"`
+	ScheduleTimeout          time.Duration `env:"SCHEDULE_TIMEOUT" envDefault:"10s"`
+	AvailabilityTimeout      time.Duration `env:"AVAILABILITY_TIMEOUT" envDefault:"10s"`
+	AdminTimeout             time.Duration `env:"ADMIN_TIMEOUT" envDefault:"5s"`
+	WaitlistTimeout          time.Duration `env:"WAITLIST_TIMEOUT" envDefault:"10s"`
+
+	// IdempotencyKeyTTL is how long idempotency keys are kept before a repeated request counts as a new one
+	IdempotencyKeyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+
+	// DefaultLaunchCapacity is the number of seats on a flight from a launchpad without its own capacity. 0 means unlimited
+	DefaultLaunchCapacity int `env:"DEFAULT_LAUNCH_CAPACITY" envDefault:"100"`
+
+	// SchedulerStrategy is either "rotation" or "timetable"
+	SchedulerStrategy string `env:"SCHEDULER_STRATEGY" envDefault:"rotation"`
+
 	DBName     string `env:"DB_NAME"`
 	DBUser     string `env:"DB_USER"`
 	DBPassword string `env:"DB_PASSWORD"`
+	DBHost     string `env:"DB_HOST" envDefault:"postgresdb"`
+	DBPort     int    `env:"DB_PORT" envDefault:"5432"`
+	DBSSLMode  string `env:"DB_SSLMODE" envDefault:"disable"`
+	// DBURL is a full connection URL. When set, it's used instead of the separate DB_* connection settings
+	DBURL            string        `env:"DB_URL"`
+	DBMinConns       int           `env:"DB_MIN_CONNS" envDefault:"0"`
+	DBMaxConns       int           `env:"DB_MAX_CONNS" envDefault:"10"`
+	DBConnectTimeout time.Duration `env:"DB_CONNECT_TIMEOUT" envDefault:"5s"`
+
+	SpaceXBaseURL                  string        `env:"SPACEX_BASE_URL" envDefault:"https://api.spacexdata.com/"`
+	SpaceXHTTPTimeout              time.Duration `env:"SPACEX_HTTP_TIMEOUT" envDefault:"4s"`
+	SpaceXLaunchpadsCacheTTL       time.Duration `env:"SPACEX_LAUNCHPADS_CACHE_TTL" envDefault:"1h"`
+	SpaceXUpcomingLaunchesCacheTTL time.Duration `env:"SPACEX_UPCOMING_LAUNCHES_CACHE_TTL" envDefault:"5m"`
+	SpaceXRetryMaxAttempts         int           `env:"SPACEX_RETRY_MAX_ATTEMPTS" envDefault:"3"`
+	SpaceXRetryBaseDelay           time.Duration `env:"SPACEX_RETRY_BASE_DELAY" envDefault:"200ms"`
+	SpaceXRetryMaxDelay            time.Duration `env:"SPACEX_RETRY_MAX_DELAY" envDefault:"2s"`
+	SpaceXBreakerFailureThreshold  int           `env:"SPACEX_BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	SpaceXBreakerOpenTimeout       time.Duration `env:"SPACEX_BREAKER_OPEN_TIMEOUT" envDefault:"30s"`
+	// SpaceXFetchTimeout limits refreshing the cached SpaceX data, which isn't cancelled with the requests waiting for it
+	SpaceXFetchTimeout time.Duration `env:"SPACEX_FETCH_TIMEOUT" envDefault:"30s"`
+}
+
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Validate checks the configuration and reports all the problems found at once.
+func (c Config) Validate() error {
+	var problems []string
+
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		problems = append(problems, fmt.Sprintf("LISTEN_ADDR should be in host:port form, got %q", c.ListenAddr))
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"READ_HEADER_TIMEOUT", c.ReadHeaderTimeout},
+		{"READ_TIMEOUT", c.ReadTimeout},
+		{"WRITE_TIMEOUT", c.WriteTimeout},
+		{"IDLE_TIMEOUT", c.IdleTimeout},
+		{"SHUTDOWN_GRACE", c.ShutdownGrace},
+		{"BOOKINGS_TIMEOUT", c.BookingsTimeout},
+		{"BOOKING_TIMEOUT", c.BookingTimeout},
+		{"BOOK_FLIGHT_TIMEOUT", c.BookFlightTimeout},
+		{"BOOKING_DELETE_TIMEOUT", c.BookingDeleteTimeout},
+		{"BOOKING_RESCHEDULE_TIMEOUT", c.BookingRescheduleTimeout},
+		{"DESTINATIONS_TIMEOUT", c.DestinationsTimeout},
+		{"LAUNCHPADS_TIMEOUT", c.LaunchpadsTimeout},
+		{"SCHEDULE_TIMEOUT", c.ScheduleTimeout},
+		{"AVAILABILITY_TIMEOUT", c.AvailabilityTimeout},
+		{"ADMIN_TIMEOUT", c.AdminTimeout},
+		{"WAITLIST_TIMEOUT", c.WaitlistTimeout},
+	} {
+		if timeout.value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s should be positive", timeout.name))
+		}
+	}
+	if c.SchedulerStrategy != "rotation" && c.SchedulerStrategy != "timetable" {
+		problems = append(problems, fmt.Sprintf("SCHEDULER_STRATEGY should be rotation or timetable, got %q", c.SchedulerStrategy))
+	}
+	if c.DefaultLaunchCapacity < 0 {
+		problems = append(problems, fmt.Sprintf("DEFAULT_LAUNCH_CAPACITY can't be negative, got %d", c.DefaultLaunchCapacity))
+	}
+	if c.MaxHeaderBytes < 1 {
+		problems = append(problems, fmt.Sprintf("MAX_HEADER_BYTES should be at least 1, got %d", c.MaxHeaderBytes))
+	}
+	if c.MaxBodyBytes < 1 {
+		problems = append(problems, fmt.Sprintf("MAX_BODY_BYTES should be at least 1, got %d", c.MaxBodyBytes))
+	}
+	if c.IdempotencyKeyTTL <= 0 {
+		problems = append(problems, "IDEMPOTENCY_KEY_TTL should be positive")
+	}
+
+	// a single SpaceX request has to fit into the requests asking SpaceX, otherwise they time out first
+	// and answer 503 although SpaceX would have answered
+	if c.SpaceXHTTPTimeout <= 0 {
+		problems = append(problems, "SPACEX_HTTP_TIMEOUT should be positive")
+	} else {
+		for _, timeout := range []struct {
+			name  string
+			value time.Duration
+		}{
+			{"BOOK_FLIGHT_TIMEOUT", c.BookFlightTimeout},
+			{"BOOKING_DELETE_TIMEOUT", c.BookingDeleteTimeout},
+			{"BOOKING_RESCHEDULE_TIMEOUT", c.BookingRescheduleTimeout},
+			{"LAUNCHPADS_TIMEOUT", c.LaunchpadsTimeout},
+			{"SCHEDULE_TIMEOUT", c.ScheduleTimeout},
+			{"AVAILABILITY_TIMEOUT", c.AvailabilityTimeout},
+			{"WAITLIST_TIMEOUT", c.WaitlistTimeout},
+		} {
+			if timeout.value > 0 && timeout.value <= c.SpaceXHTTPTimeout {
+				problems = append(problems, fmt.Sprintf("SPACEX_HTTP_TIMEOUT should be shorter than %s, got %s", timeout.name, c.SpaceXHTTPTimeout))
+			}
+		}
+	}
+	if c.SpaceXFetchTimeout > 0 && c.SpaceXFetchTimeout < c.SpaceXHTTPTimeout {
+		problems = append(problems, fmt.Sprintf("SPACEX_FETCH_TIMEOUT can't be shorter than SPACEX_HTTP_TIMEOUT, got %s", c.SpaceXFetchTimeout))
+	}
+
+	if c.DBURL != "" {
+		u, err := url.Parse(c.DBURL)
+		if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+			problems = append(problems, "DB_URL should be a postgres:// URL")
+		}
+	} else {
+		if c.DBName == "" {
+			problems = append(problems, "DB_NAME is required")
+		}
+		if c.DBUser == "" {
+			problems = append(problems, "DB_USER is required")
+		}
+		if c.DBHost == "" {
+			problems = append(problems, "DB_HOST is required")
+		}
+		if c.DBPort < 1 || c.DBPort > 65535 {
+			problems = append(problems, fmt.Sprintf("DB_PORT should be between 1 and 65535, got %d", c.DBPort))
+		}
+		if !contains(sslModes, c.DBSSLMode) {
+			problems = append(problems, fmt.Sprintf("DB_SSLMODE should be one of %s, got %q", strings.Join(sslModes, ", "), c.DBSSLMode))
+		}
+	}
+
+	if c.DBMaxConns < 1 {
+		problems = append(problems, fmt.Sprintf("DB_MAX_CONNS should be at least 1, got %d", c.DBMaxConns))
+	}
+	if c.DBMinConns < 0 || c.DBMinConns > c.DBMaxConns {
+		problems = append(problems, fmt.Sprintf("DB_MIN_CONNS should be between 0 and DB_MAX_CONNS, got %d", c.DBMinConns))
+	}
+	if c.DBConnectTimeout <= 0 {
+		problems = append(problems, "DB_CONNECT_TIMEOUT should be positive")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// DBConnURL returns DB_URL if it's set, otherwise builds the URL from the separate DB_* settings.
+func (c Config) DBConnURL() string {
+	if c.DBURL != "" {
+		return c.DBURL
+	}
+
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     net.JoinHostPort(c.DBHost, strconv.Itoa(c.DBPort)),
+		Path:     c.DBName,
+		RawQuery: url.Values{"sslmode": []string{c.DBSSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+)
+
+func (s *pgstorage) CreateBookingChange(ctx context.Context, change BookingChange) (BookingChange, error) {
+	row := s.pg.QueryRow(ctx, "INSERT INTO booking_changes "+
+		"(booking_id, old_launch_date, old_launchpad_id, old_destination_id, new_launch_date, new_launchpad_id, new_destination_id) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id,changed_at",
+		change.BookingID, change.Old.LaunchDate, change.Old.LaunchpadID, change.Old.DestinationID,
+		change.New.LaunchDate, change.New.LaunchpadID, change.New.DestinationID)
+	err := row.Scan(&change.ID, &change.ChangedAt)
+	return change, err
+}
+
+func (s *pgstorage) BookingChanges(ctx context.Context, bookingID int) ([]BookingChange, error) {
+	rows, err := s.pg.Query(ctx, "SELECT id,booking_id,old_launch_date,old_launchpad_id,old_destination_id,"+
+		"new_launch_date,new_launchpad_id,new_destination_id,changed_at FROM booking_changes "+
+		"WHERE booking_id = $1 ORDER BY id", bookingID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var changes []BookingChange
+	for rows.Next() {
+		var c BookingChange
+		err = rows.Scan(&c.ID, &c.BookingID, &c.Old.LaunchDate, &c.Old.LaunchpadID, &c.Old.DestinationID,
+			&c.New.LaunchDate, &c.New.LaunchpadID, &c.New.DestinationID, &c.ChangedAt)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
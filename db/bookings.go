@@ -4,15 +4,36 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v4"
 )
 
+const bookingColumns = "id,first_name,last_name,gender,birthday,launchpad_id,destination_id,launch_date,status,cancelled_at,cancellation_reason,group_id"
+
+func scanBooking(row pgx.Row) (Booking, error) {
+	var b Booking
+	var reason *string
+	err := row.Scan(&b.ID, &b.FirstName, &b.LastName, &b.Gender, &b.Birthday, &b.LaunchpadID, &b.DestinationID, &b.LaunchDate,
+		&b.Status, &b.CancelledAt, &reason, &b.GroupID)
+	if reason != nil {
+		b.CancellationReason = *reason
+	}
+	return b, err
+}
+
 func (s *pgstorage) Bookings(ctx context.Context, filter BookingsFilter) ([]Booking, error) {
-	q := "SELECT id,first_name,last_name,gender,birthday,launchpad_id,destination_id,launch_date FROM bookings "
+	q := "SELECT " + bookingColumns + " FROM bookings "
 	var conditions []string
+	var args []interface{}
 	if !filter.LaunchDate.IsZero() {
-		conditions = append(conditions, fmt.Sprintf("CAST(launch_date as DATE) = '%s'", filter.LaunchDate.Format("2006-01-02")))
+		args = append(args, filter.LaunchDate)
+		conditions = append(conditions, fmt.Sprintf("launch_date = $%d", len(args)))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
 	}
 
 	var paginationParams []string
@@ -26,19 +47,18 @@ func (s *pgstorage) Bookings(ctx context.Context, filter BookingsFilter) ([]Book
 	}
 
 	if len(conditions) > 0 {
-		q += " WHERE "
-		for _, condition := range conditions {
-			q += condition + " "
-		}
+		q += "WHERE " + strings.Join(conditions, " AND ") + " "
 	}
 
+	q += "ORDER BY id "
+
 	if len(paginationParams) > 0 {
 		for _, paginationParam := range paginationParams {
 			q += paginationParam + " "
 		}
 	}
 
-	rows, err := s.pg.Query(ctx, q)
+	rows, err := s.pg.Query(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -47,8 +67,7 @@ func (s *pgstorage) Bookings(ctx context.Context, filter BookingsFilter) ([]Book
 
 	var bookings []Booking
 	for rows.Next() {
-		var b Booking
-		err = rows.Scan(&b.ID, &b.FirstName, &b.LastName, &b.Gender, &b.Birthday, &b.LaunchpadID, &b.DestinationID, &b.LaunchDate)
+		b, err := scanBooking(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -61,28 +80,82 @@ func (s *pgstorage) Bookings(ctx context.Context, filter BookingsFilter) ([]Book
 	return bookings, nil
 }
 
-func (s *pgstorage) CreateBooking(ctx context.Context, b Booking) error {
-	_, err := s.pg.Exec(ctx, "INSERT INTO bookings "+
-		"(first_name, last_name, gender, birthday, launchpad_id, destination_id, launch_date) VALUES "+
-		"($1, $2, $3, $4, $5, $6, $7)",
-		b.FirstName, b.LastName, b.Gender, b.Birthday, b.LaunchpadID, b.DestinationID, b.LaunchDate)
-	return err
+func (s *pgstorage) BookedLaunches(ctx context.Context, from, to time.Time) ([]BookedLaunch, error) {
+	rows, err := s.pg.Query(ctx, "SELECT launch_date,launchpad_id,destination_id,count(*) FROM bookings "+
+		"WHERE launch_date BETWEEN $1 AND $2 AND status <> $3 "+
+		"GROUP BY launch_date,launchpad_id,destination_id "+
+		"ORDER BY launch_date,min(id)", from, to, BookingStatusCancelled)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var launches []BookedLaunch
+	for rows.Next() {
+		var l BookedLaunch
+		err = rows.Scan(&l.LaunchDate, &l.LaunchpadID, &l.DestinationID, &l.Passengers)
+		if err != nil {
+			return nil, err
+		}
+		launches = append(launches, l)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return launches, nil
+}
+
+func (s *pgstorage) Booking(ctx context.Context, id int) (Booking, error) {
+	b, err := scanBooking(s.pg.QueryRow(ctx, "SELECT "+bookingColumns+" FROM bookings WHERE id = $1", id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Booking{}, ErrNotFound
+		}
+		return Booking{}, err
+	}
+	return b, nil
+}
+
+func (s *pgstorage) CreateBooking(ctx context.Context, b Booking) (Booking, error) {
+	row := s.pg.QueryRow(ctx, "INSERT INTO bookings "+
+		"(first_name, last_name, gender, birthday, launchpad_id, destination_id, launch_date, group_id) VALUES "+
+		"($1, $2, $3, $4, $5, $6, $7, $8) "+
+		"RETURNING "+bookingColumns,
+		b.FirstName, b.LastName, b.Gender, b.Birthday, b.LaunchpadID, b.DestinationID, b.LaunchDate, b.GroupID)
+	return scanBooking(row)
+}
+
+func (s *pgstorage) CreateBookingGroup(ctx context.Context) (int, error) {
+	var id int
+	err := s.pg.QueryRow(ctx, "INSERT INTO booking_groups DEFAULT VALUES RETURNING id").Scan(&id)
+	return id, err
 }
 
-func (s *pgstorage) BookingExists(ctx context.Context, id int) (bool, error) {
-	row := s.pg.QueryRow(ctx, "SELECT id FROM bookings WHERE id = $1", id)
-	var val int
-	err := row.Scan(&val)
+func (s *pgstorage) CancelBooking(ctx context.Context, id int, reason string) (Booking, error) {
+	row := s.pg.QueryRow(ctx, "UPDATE bookings SET status = $2, cancelled_at = now(), cancellation_reason = NULLIF($3, '') "+
+		"WHERE id = $1 AND status = $4 RETURNING "+bookingColumns, id, BookingStatusCancelled, reason, BookingStatusConfirmed)
+	b, err := scanBooking(row)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return false, nil
+			return Booking{}, ErrNotFound
 		}
-		return false, err
+		return Booking{}, err
 	}
-	return true, nil
+	return b, nil
 }
 
-func (s *pgstorage) BookingDelete(ctx context.Context, id int) error {
-	_, err := s.pg.Exec(ctx, "DELETE FROM bookings WHERE id = $1", id)
-	return err
+func (s *pgstorage) RescheduleBooking(ctx context.Context, id int, flight Flight) (Booking, error) {
+	row := s.pg.QueryRow(ctx, "UPDATE bookings SET launch_date = $2, launchpad_id = $3, destination_id = $4 "+
+		"WHERE id = $1 AND status = $5 RETURNING "+bookingColumns,
+		id, flight.LaunchDate, flight.LaunchpadID, flight.DestinationID, BookingStatusConfirmed)
+	b, err := scanBooking(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Booking{}, ErrNotFound
+		}
+		return Booking{}, err
+	}
+	return b, nil
 }
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+)
+
+func (s *pgstorage) LaunchpadCapacities(ctx context.Context) (map[string]int, error) {
+	rows, err := s.pg.Query(ctx, "SELECT launchpad_id,seats FROM launchpad_capacities")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	capacities := make(map[string]int)
+	for rows.Next() {
+		var launchpadID string
+		var seats int
+		err = rows.Scan(&launchpadID, &seats)
+		if err != nil {
+			return nil, err
+		}
+		capacities[launchpadID] = seats
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return capacities, nil
+}
+
+func (s *pgstorage) SetLaunchpadCapacity(ctx context.Context, launchpadID string, seats int) error {
+	_, err := s.pg.Exec(ctx, "INSERT INTO launchpad_capacities (launchpad_id, seats) VALUES ($1, $2) "+
+		"ON CONFLICT (launchpad_id) DO UPDATE SET seats = EXCLUDED.seats", launchpadID, seats)
+	return err
+}
+
+func (s *pgstorage) DeleteLaunchpadCapacity(ctx context.Context, launchpadID string) error {
+	tag, err := s.pg.Exec(ctx, "DELETE FROM launchpad_capacities WHERE launchpad_id = $1", launchpadID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
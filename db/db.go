@@ -2,27 +2,112 @@ package db
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+var (
+	// ErrNotFound is returned when the requested row doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists is returned when a row with the same unique value exists already.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrInUse is returned when a row can't be deleted because other rows depend on it.
+	ErrInUse = errors.New("in use")
+)
+
 type Storage interface {
 	Bookings(ctx context.Context, filter BookingsFilter) ([]Booking, error)
-	CreateBooking(ctx context.Context, booking Booking) error
+	Booking(ctx context.Context, id int) (Booking, error)
+	// BookedLaunches returns the launches having bookings between from and to inclusive,
+	// ordered by day and then by the first booking made. Cancelled bookings are left out.
+	BookedLaunches(ctx context.Context, from, to time.Time) ([]BookedLaunch, error)
+	// CreateBooking inserts the booking and returns the stored row with its generated ID.
+	CreateBooking(ctx context.Context, booking Booking) (Booking, error)
+	// CreateBookingGroup creates a group linking the bookings made together and returns its ID.
+	CreateBookingGroup(ctx context.Context) (int, error)
 	Destinations(ctx context.Context) ([]Destination, error)
-	BookingExists(ctx context.Context, id int) (bool, error)
-	BookingDelete(ctx context.Context, id int) error
+	CreateDestination(ctx context.Context, name string) (Destination, error)
+	UpdateDestination(ctx context.Context, id int, name string) (Destination, error)
+	// DeleteDestination deletes the destination unless bookings not cancelled, timetable entries, schedule overrides
+	// or waitlist entries not promoted yet use it on from or later. It returns ErrInUse then.
+	DeleteDestination(ctx context.Context, id int, from time.Time) error
+	// CancelBooking cancels the confirmed booking and returns it. It returns ErrNotFound when there's
+	// no confirmed booking with the ID.
+	CancelBooking(ctx context.Context, id int, reason string) (Booking, error)
+	// RescheduleBooking moves the confirmed booking to the flight and returns it. It returns ErrNotFound when there's
+	// no confirmed booking with the ID.
+	RescheduleBooking(ctx context.Context, id int, flight Flight) (Booking, error)
+	CreateBookingChange(ctx context.Context, change BookingChange) (BookingChange, error)
+	// BookingChanges returns the history of the booking, oldest change first.
+	BookingChanges(ctx context.Context, bookingID int) ([]BookingChange, error)
+	CreateWaitlistEntry(ctx context.Context, entry WaitlistEntry) (WaitlistEntry, error)
+	// OldestWaitlistEntry returns the entry waiting the longest for the launch. Passengers are ignored.
+	// It returns ErrNotFound when nobody waits for it.
+	OldestWaitlistEntry(ctx context.Context, launch BookedLaunch) (WaitlistEntry, error)
+	// PromoteWaitlistEntry records that the entry got the booking, so the customer can be notified.
+	PromoteWaitlistEntry(ctx context.Context, id, bookingID int) error
+	// ReserveIdempotencyKey stores the key for a request in progress. When the key is already taken
+	// it returns the stored key and false. A key older than expireAfter is reserved anew, and so is a key
+	// of the same request still in progress after abandonAfter, as the request holding it must have died.
+	// A zero duration disables the check.
+	ReserveIdempotencyKey(ctx context.Context, key, requestHash string, abandonAfter, expireAfter time.Duration) (IdempotencyKey, bool, error)
+	SaveIdempotentResponse(ctx context.Context, key string, resp IdempotentResponse) error
+	DeleteIdempotencyKey(ctx context.Context, key string) error
+	// DeleteExpiredIdempotencyKeys deletes the keys older than expireAfter and returns how many were deleted.
+	DeleteExpiredIdempotencyKeys(ctx context.Context, expireAfter time.Duration) (int64, error)
+	// TimetableEntries returns the timetable between from and to inclusive, ordered by day and launchpad.
+	TimetableEntries(ctx context.Context, from, to time.Time) ([]TimetableEntry, error)
+	// SetTimetableEntry pins the destination to the launchpad on the day, replacing the existing entry.
+	SetTimetableEntry(ctx context.Context, entry TimetableEntry) error
+	DeleteTimetableEntry(ctx context.Context, launchDate time.Time, launchpadID string) error
+	// ScheduleOverrides returns the overrides between from and to inclusive, ordered by day and launchpad.
+	ScheduleOverrides(ctx context.Context, from, to time.Time) ([]ScheduleOverride, error)
+	// SetScheduleOverride stores the override for the launchpad on the day, replacing the existing one.
+	SetScheduleOverride(ctx context.Context, override ScheduleOverride) error
+	DeleteScheduleOverride(ctx context.Context, launchDate time.Time, launchpadID string) error
+	// LaunchpadCapacities returns the seats on the flights from each launchpad. Launchpads without a row use the default capacity.
+	LaunchpadCapacities(ctx context.Context) (map[string]int, error)
+	SetLaunchpadCapacity(ctx context.Context, launchpadID string, seats int) error
+	DeleteLaunchpadCapacity(ctx context.Context, launchpadID string) error
+	// WithLaunchDayLock runs fn in a single transaction holding an exclusive lock
+	// on the launch day, so checks and writes done through tx can't interleave
+	// with other bookings for the same day.
+	WithLaunchDayLock(ctx context.Context, launchDate time.Time, fn func(tx Storage) error) error
+	// WithLaunchDaysLock is WithLaunchDayLock for a change touching several days.
+	WithLaunchDaysLock(ctx context.Context, launchDates []time.Time, fn func(tx Storage) error) error
+}
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx, so the same storage
+// code can run inside and outside of a transaction.
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
 }
 
 type pgstorage struct {
-	pg *pgxpool.Pool
+	pg querier
 }
 
 func NewPGStorage(pool *pgxpool.Pool) Storage {
 	return &pgstorage{pg: pool}
 }
 
+const (
+	BookingStatusConfirmed = "confirmed"
+	BookingStatusCancelled = "cancelled"
+	BookingStatusFlown     = "flown"
+	BookingStatusNoShow    = "no_show"
+)
+
+// BookingStatuses lists all the statuses a booking can have.
+var BookingStatuses = []string{BookingStatusConfirmed, BookingStatusCancelled, BookingStatusFlown, BookingStatusNoShow}
+
 type Booking struct {
 	ID            int
 	FirstName     string
@@ -32,15 +117,93 @@ type Booking struct {
 	LaunchpadID   string
 	DestinationID int
 	LaunchDate    time.Time
+	Status        string
+	// CancelledAt and CancellationReason are set for cancelled bookings
+	CancelledAt        *time.Time
+	CancellationReason string
+	// GroupID links the bookings made together in one group booking
+	GroupID *int
+}
+
+// Flight is where a booking flies: the day, the launchpad and the destination.
+type Flight struct {
+	LaunchDate    time.Time
+	LaunchpadID   string
+	DestinationID int
+}
+
+// BookingChange records a booking moved from one flight to another.
+type BookingChange struct {
+	ID        int
+	BookingID int
+	Old       Flight
+	New       Flight
+	ChangedAt time.Time
+}
+
+// WaitlistEntry is a booking waiting for a seat on a full or unavailable flight.
+type WaitlistEntry struct {
+	ID            int
+	FirstName     string
+	LastName      string
+	Gender        string
+	Birthday      time.Time
+	LaunchpadID   string
+	DestinationID int
+	LaunchDate    time.Time
+	CreatedAt     time.Time
+	// PromotedBookingID and PromotedAt are set once the entry got a booking
+	PromotedBookingID *int
+	PromotedAt        *time.Time
+}
+
+// BookedLaunch groups the bookings flying together, on the same day from the same launchpad to the same destination.
+type BookedLaunch struct {
+	LaunchDate    time.Time
+	LaunchpadID   string
+	DestinationID int
+	Passengers    int
+}
+
+// TimetableEntry pins a destination to a launchpad on a day for the timetable scheduling strategy.
+type TimetableEntry struct {
+	LaunchDate    time.Time
+	LaunchpadID   string
+	DestinationID int
+}
+
+// ScheduleOverride is set by ops for a launchpad on a day and takes precedence over the scheduling strategy.
+// Either DestinationID forces the destination, or Closed takes the launchpad out of service.
+type ScheduleOverride struct {
+	LaunchDate    time.Time
+	LaunchpadID   string
+	DestinationID *int
+	Closed        bool
 }
 
 type BookingsFilter struct {
 	LaunchDate time.Time
-	Offset     int
-	Limit      int
+	// Status limits the bookings to the status when set
+	Status string
+	Offset int
+	Limit  int
 }
 
 type Destination struct {
 	ID   int
 	Name string
 }
+
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+	// Response is nil while the first request with the key is still in progress
+	Response  *IdempotentResponse
+	CreatedAt time.Time
+}
+
+type IdempotentResponse struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
@@ -2,10 +2,19 @@ package db
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 )
 
+// uniqueViolation is the Postgres error code for unique constraint violations
+const uniqueViolation = "23505"
+
 func (s *pgstorage) Destinations(ctx context.Context) ([]Destination, error) {
-	rows, err := s.pg.Query(ctx, "SELECT id,name FROM destinations")
+	// inside a booking transaction FOR SHARE keeps the destinations from being deleted until the booking is stored
+	rows, err := s.pg.Query(ctx, "SELECT id,name FROM destinations ORDER BY id FOR SHARE")
 	if err != nil {
 		return nil, err
 	}
@@ -27,3 +36,72 @@ func (s *pgstorage) Destinations(ctx context.Context) ([]Destination, error) {
 
 	return destinations, nil
 }
+
+func (s *pgstorage) CreateDestination(ctx context.Context, name string) (Destination, error) {
+	row := s.pg.QueryRow(ctx, "INSERT INTO destinations (name) VALUES ($1) RETURNING id,name", name)
+	var destination Destination
+	err := row.Scan(&destination.ID, &destination.Name)
+	if err != nil {
+		return Destination{}, uniqueErr(err)
+	}
+	return destination, nil
+}
+
+func (s *pgstorage) UpdateDestination(ctx context.Context, id int, name string) (Destination, error) {
+	row := s.pg.QueryRow(ctx, "UPDATE destinations SET name = $2 WHERE id = $1 RETURNING id,name", id, name)
+	var destination Destination
+	err := row.Scan(&destination.ID, &destination.Name)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Destination{}, ErrNotFound
+		}
+		return Destination{}, uniqueErr(err)
+	}
+	return destination, nil
+}
+
+func (s *pgstorage) DeleteDestination(ctx context.Context, id int, from time.Time) error {
+	tx, err := s.pg.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the row lock makes the check and the delete atomic for concurrent deletes of the same destination
+	row := tx.QueryRow(ctx, "SELECT id FROM destinations WHERE id = $1 FOR UPDATE", id)
+	if err = row.Scan(&id); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	// none of these tables has a foreign key to destinations, so the rows would be left pointing to nothing
+	var used bool
+	err = tx.QueryRow(ctx, "SELECT "+
+		"EXISTS (SELECT 1 FROM bookings WHERE destination_id = $1 AND launch_date >= $2 AND status <> $3) "+
+		"OR EXISTS (SELECT 1 FROM timetable WHERE destination_id = $1 AND launch_date >= $2) "+
+		"OR EXISTS (SELECT 1 FROM schedule_overrides WHERE destination_id = $1 AND launch_date >= $2) "+
+		"OR EXISTS (SELECT 1 FROM waitlist WHERE destination_id = $1 AND launch_date >= $2 AND promoted_booking_id IS NULL)",
+		id, from, BookingStatusCancelled).Scan(&used)
+	if err != nil {
+		return err
+	}
+	if used {
+		return ErrInUse
+	}
+
+	if _, err = tx.Exec(ctx, "DELETE FROM destinations WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func uniqueErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrAlreadyExists
+	}
+	return err
+}
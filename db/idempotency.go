@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (s *pgstorage) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, abandonAfter, expireAfter time.Duration) (IdempotencyKey, bool, error) {
+	// an expired key is reserved anew whatever it was used for, an abandoned one only for the same request.
+	// The update locks the row, so of several requests taking a key over only one sees it still old enough
+	reserved := IdempotencyKey{Key: key, RequestHash: requestHash}
+	err := s.pg.QueryRow(ctx, "INSERT INTO idempotency_keys (key, request_hash) VALUES ($1, $2) "+
+		"ON CONFLICT (key) DO UPDATE SET request_hash = EXCLUDED.request_hash, response_status = NULL, "+
+		"response_headers = NULL, response_body = NULL, created_at = now() "+
+		"WHERE ($4::interval > interval '0' AND idempotency_keys.created_at < now() - $4::interval) "+
+		"OR ($3::interval > interval '0' AND idempotency_keys.response_status IS NULL "+
+		"AND idempotency_keys.request_hash = EXCLUDED.request_hash AND idempotency_keys.created_at < now() - $3::interval) "+
+		"RETURNING created_at",
+		key, requestHash, abandonAfter, expireAfter).Scan(&reserved.CreatedAt)
+	if err == nil {
+		return reserved, true, nil
+	}
+	if err != pgx.ErrNoRows {
+		return IdempotencyKey{}, false, err
+	}
+
+	row := s.pg.QueryRow(ctx, "SELECT request_hash,response_status,response_headers,response_body,created_at FROM idempotency_keys WHERE key = $1", key)
+	var (
+		stored  = IdempotencyKey{Key: key}
+		status  *int
+		headers []byte
+		body    []byte
+	)
+	err = row.Scan(&stored.RequestHash, &status, &headers, &body, &stored.CreatedAt)
+	if err != nil {
+		return IdempotencyKey{}, false, err
+	}
+
+	if status != nil {
+		stored.Response = &IdempotentResponse{Status: *status, Body: body}
+		if headers != nil {
+			if err = json.Unmarshal(headers, &stored.Response.Headers); err != nil {
+				return IdempotencyKey{}, false, err
+			}
+		}
+	}
+
+	return stored, false, nil
+}
+
+func (s *pgstorage) SaveIdempotentResponse(ctx context.Context, key string, resp IdempotentResponse) error {
+	headers, err := json.Marshal(resp.Headers)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.pg.Exec(ctx, "UPDATE idempotency_keys SET response_status = $2, response_headers = $3, response_body = $4 WHERE key = $1",
+		key, resp.Status, headers, resp.Body)
+	return err
+}
+
+func (s *pgstorage) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	_, err := s.pg.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key)
+	return err
+}
+
+func (s *pgstorage) DeleteExpiredIdempotencyKeys(ctx context.Context, expireAfter time.Duration) (int64, error) {
+	tag, err := s.pg.Exec(ctx, "DELETE FROM idempotency_keys WHERE created_at < now() - $1::interval", expireAfter)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
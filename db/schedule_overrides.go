@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+func (s *pgstorage) ScheduleOverrides(ctx context.Context, from, to time.Time) ([]ScheduleOverride, error) {
+	rows, err := s.pg.Query(ctx, "SELECT launch_date,launchpad_id,destination_id,closed FROM schedule_overrides "+
+		"WHERE launch_date BETWEEN $1 AND $2 ORDER BY launch_date,launchpad_id", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var overrides []ScheduleOverride
+	for rows.Next() {
+		var override ScheduleOverride
+		err = rows.Scan(&override.LaunchDate, &override.LaunchpadID, &override.DestinationID, &override.Closed)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, override)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+func (s *pgstorage) SetScheduleOverride(ctx context.Context, override ScheduleOverride) error {
+	_, err := s.pg.Exec(ctx, "INSERT INTO schedule_overrides (launch_date, launchpad_id, destination_id, closed) VALUES ($1, $2, $3, $4) "+
+		"ON CONFLICT (launch_date, launchpad_id) DO UPDATE SET destination_id = EXCLUDED.destination_id, closed = EXCLUDED.closed",
+		override.LaunchDate, override.LaunchpadID, override.DestinationID, override.Closed)
+	return err
+}
+
+func (s *pgstorage) DeleteScheduleOverride(ctx context.Context, launchDate time.Time, launchpadID string) error {
+	tag, err := s.pg.Exec(ctx, "DELETE FROM schedule_overrides WHERE launch_date = $1 AND launchpad_id = $2", launchDate, launchpadID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+func (s *pgstorage) TimetableEntries(ctx context.Context, from, to time.Time) ([]TimetableEntry, error) {
+	rows, err := s.pg.Query(ctx, "SELECT launch_date,launchpad_id,destination_id FROM timetable "+
+		"WHERE launch_date BETWEEN $1 AND $2 ORDER BY launch_date,launchpad_id", from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entries []TimetableEntry
+	for rows.Next() {
+		var entry TimetableEntry
+		err = rows.Scan(&entry.LaunchDate, &entry.LaunchpadID, &entry.DestinationID)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *pgstorage) SetTimetableEntry(ctx context.Context, entry TimetableEntry) error {
+	_, err := s.pg.Exec(ctx, "INSERT INTO timetable (launch_date, launchpad_id, destination_id) VALUES ($1, $2, $3) "+
+		"ON CONFLICT (launch_date, launchpad_id) DO UPDATE SET destination_id = EXCLUDED.destination_id",
+		entry.LaunchDate, entry.LaunchpadID, entry.DestinationID)
+	return err
+}
+
+func (s *pgstorage) DeleteTimetableEntry(ctx context.Context, launchDate time.Time, launchpadID string) error {
+	tag, err := s.pg.Exec(ctx, "DELETE FROM timetable WHERE launch_date = $1 AND launchpad_id = $2", launchDate, launchpadID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
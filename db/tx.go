@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// launchDayLockNamespace is the first key of the advisory locks taken per launch day.
+// It keeps them apart from any other advisory locks used in the database.
+const launchDayLockNamespace = 1
+
+func (s *pgstorage) WithLaunchDayLock(ctx context.Context, launchDate time.Time, fn func(tx Storage) error) error {
+	return s.WithLaunchDaysLock(ctx, []time.Time{launchDate}, fn)
+}
+
+func (s *pgstorage) WithLaunchDaysLock(ctx context.Context, launchDates []time.Time, fn func(tx Storage) error) error {
+	tx, err := s.pg.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// the locks are always taken in the same order, so two transactions locking the same days can't deadlock
+	keys := make([]int, 0, len(launchDates))
+	for _, launchDate := range launchDates {
+		keys = append(keys, launchDayKey(launchDate))
+	}
+	sort.Ints(keys)
+	for i, key := range keys {
+		if i > 0 && key == keys[i-1] {
+			continue
+		}
+		_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1::int, $2::int)", launchDayLockNamespace, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = fn(&pgstorage{pg: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// launchDayKey returns the number of days since the Unix epoch for the launch date.
+func launchDayKey(launchDate time.Time) int {
+	day := time.Date(launchDate.Year(), launchDate.Month(), launchDate.Day(), 0, 0, 0, 0, time.UTC)
+	return int(day.Unix() / int64(24*time.Hour/time.Second))
+}
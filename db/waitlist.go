@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const waitlistColumns = "id,first_name,last_name,gender,birthday,launchpad_id,destination_id,launch_date,created_at,promoted_booking_id,promoted_at"
+
+func scanWaitlistEntry(row pgx.Row) (WaitlistEntry, error) {
+	var e WaitlistEntry
+	err := row.Scan(&e.ID, &e.FirstName, &e.LastName, &e.Gender, &e.Birthday, &e.LaunchpadID, &e.DestinationID, &e.LaunchDate,
+		&e.CreatedAt, &e.PromotedBookingID, &e.PromotedAt)
+	return e, err
+}
+
+func (s *pgstorage) CreateWaitlistEntry(ctx context.Context, e WaitlistEntry) (WaitlistEntry, error) {
+	row := s.pg.QueryRow(ctx, "INSERT INTO waitlist "+
+		"(first_name, last_name, gender, birthday, launchpad_id, destination_id, launch_date) VALUES "+
+		"($1, $2, $3, $4, $5, $6, $7) "+
+		"RETURNING "+waitlistColumns,
+		e.FirstName, e.LastName, e.Gender, e.Birthday, e.LaunchpadID, e.DestinationID, e.LaunchDate)
+	return scanWaitlistEntry(row)
+}
+
+func (s *pgstorage) OldestWaitlistEntry(ctx context.Context, launch BookedLaunch) (WaitlistEntry, error) {
+	row := s.pg.QueryRow(ctx, "SELECT "+waitlistColumns+" FROM waitlist "+
+		"WHERE launch_date = $1 AND launchpad_id = $2 AND destination_id = $3 AND promoted_booking_id IS NULL "+
+		"ORDER BY created_at, id LIMIT 1 FOR UPDATE",
+		launch.LaunchDate, launch.LaunchpadID, launch.DestinationID)
+	e, err := scanWaitlistEntry(row)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return WaitlistEntry{}, ErrNotFound
+		}
+		return WaitlistEntry{}, err
+	}
+	return e, nil
+}
+
+func (s *pgstorage) PromoteWaitlistEntry(ctx context.Context, id, bookingID int) error {
+	tag, err := s.pg.Exec(ctx, "UPDATE waitlist SET promoted_booking_id = $2, promoted_at = now() "+
+		"WHERE id = $1 AND promoted_booking_id IS NULL", id, bookingID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
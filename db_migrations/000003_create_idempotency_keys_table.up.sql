@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR (255) PRIMARY KEY,
+    request_hash VARCHAR (64) NOT NULL,
+    response_status int,
+    response_headers jsonb,
+    response_body bytea,
+    created_at timestamp NOT NULL DEFAULT now()
+);
@@ -0,0 +1 @@
+DROP TABLE timetable;
\ No newline at end of file
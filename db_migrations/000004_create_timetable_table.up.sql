@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS timetable (
+    launch_date date NOT NULL,
+    launchpad_id varchar (30) NOT NULL,
+    destination_id int NOT NULL,
+    PRIMARY KEY (launch_date, launchpad_id)
+);
@@ -0,0 +1 @@
+DROP TABLE schedule_overrides;
\ No newline at end of file
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS schedule_overrides (
+    launch_date date NOT NULL,
+    launchpad_id varchar (30) NOT NULL,
+    destination_id int,
+    closed boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (launch_date, launchpad_id),
+    CHECK ((destination_id IS NOT NULL) <> closed)
+);
@@ -0,0 +1 @@
+DROP TABLE launchpad_capacities;
\ No newline at end of file
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS launchpad_capacities (
+    launchpad_id varchar (30) PRIMARY KEY,
+    seats int NOT NULL CHECK (seats > 0)
+);
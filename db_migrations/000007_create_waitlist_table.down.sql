@@ -0,0 +1 @@
+DROP TABLE waitlist;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS waitlist (
+    id serial PRIMARY KEY,
+    first_name VARCHAR (50) NOT NULL,
+    last_name VARCHAR (50) NOT NULL,
+    gender VARCHAR (10) NOT NULL,
+    birthday date NOT NULL,
+    launchpad_id varchar (30) NOT NULL,
+    destination_id int NOT NULL,
+    launch_date date NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    promoted_booking_id int,
+    promoted_at timestamptz,
+    notified_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS waitlist_flight_idx ON waitlist (launch_date, launchpad_id, destination_id) WHERE promoted_booking_id IS NULL;
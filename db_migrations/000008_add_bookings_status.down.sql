@@ -0,0 +1 @@
+ALTER TABLE bookings DROP COLUMN cancellation_reason, DROP COLUMN cancelled_at, DROP COLUMN status;
\ No newline at end of file
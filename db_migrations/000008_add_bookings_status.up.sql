@@ -0,0 +1,5 @@
+ALTER TABLE bookings
+    ADD COLUMN IF NOT EXISTS status varchar (20) NOT NULL DEFAULT 'confirmed'
+        CHECK (status IN ('confirmed', 'cancelled', 'flown', 'no_show')),
+    ADD COLUMN IF NOT EXISTS cancelled_at timestamptz,
+    ADD COLUMN IF NOT EXISTS cancellation_reason text;
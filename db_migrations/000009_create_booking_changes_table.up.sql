@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS booking_changes (
+    id serial PRIMARY KEY,
+    booking_id int NOT NULL,
+    old_launch_date date NOT NULL,
+    old_launchpad_id varchar (30) NOT NULL,
+    old_destination_id int NOT NULL,
+    new_launch_date date NOT NULL,
+    new_launchpad_id varchar (30) NOT NULL,
+    new_destination_id int NOT NULL,
+    changed_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS booking_changes_booking_id_idx ON booking_changes (booking_id);
@@ -0,0 +1,2 @@
+ALTER TABLE bookings DROP COLUMN group_id;
+DROP TABLE booking_groups;
\ No newline at end of file
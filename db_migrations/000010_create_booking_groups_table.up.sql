@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS booking_groups (
+    id serial PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+ALTER TABLE bookings ADD COLUMN IF NOT EXISTS group_id int REFERENCES booking_groups (id);
+
+CREATE INDEX IF NOT EXISTS bookings_group_id_idx ON bookings (group_id);
@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"space-trouble-bookings-api/api"
 	"space-trouble-bookings-api/config"
 	"space-trouble-bookings-api/db"
+	"space-trouble-bookings-api/schedule"
 	"space-trouble-bookings-api/spacex"
 	"syscall"
 	"time"
@@ -42,10 +44,20 @@ func main() {
 		l.Fatal(err)
 	}
 
-	connURL := fmt.Sprintf("postgres://%s:%s@postgresdb:5432/%s?sslmode=disable",
-		cfg.DBUser, cfg.DBPassword, cfg.DBName)
+	if err = cfg.Validate(); err != nil {
+		l.Fatal(err)
+	}
 
-	pgpool, err := pgxpool.Connect(context.TODO(), connURL)
+	connURL := cfg.DBConnURL()
+	poolCfg, err := pgxpool.ParseConfig(connURL)
+	if err != nil {
+		l.Fatal(err)
+	}
+	poolCfg.MinConns = int32(cfg.DBMinConns)
+	poolCfg.MaxConns = int32(cfg.DBMaxConns)
+	poolCfg.ConnConfig.ConnectTimeout = cfg.DBConnectTimeout
+
+	pgpool, err := pgxpool.ConnectConfig(context.TODO(), poolCfg)
 	if err != nil {
 		l.Fatal(err)
 	}
@@ -63,20 +75,94 @@ func main() {
 		}
 	}
 
-	spacexClient := spacex.NewClient(&http.Client{Timeout: 15 * time.Second})
-	handlers := api.NewAPI(spacexClient, db.NewPGStorage(pgpool), l)
+	spacexClient := spacex.NewClient(&http.Client{Timeout: cfg.SpaceXHTTPTimeout},
+		spacex.WithBaseURL(cfg.SpaceXBaseURL),
+		spacex.WithRetry(spacex.RetryConfig{
+			MaxAttempts: cfg.SpaceXRetryMaxAttempts,
+			BaseDelay:   cfg.SpaceXRetryBaseDelay,
+			MaxDelay:    cfg.SpaceXRetryMaxDelay,
+		}),
+		spacex.WithCircuitBreaker(spacex.BreakerConfig{
+			FailureThreshold: cfg.SpaceXBreakerFailureThreshold,
+			OpenTimeout:      cfg.SpaceXBreakerOpenTimeout,
+		}),
+	)
+	spacexClient = spacex.NewCachingClient(spacexClient, spacex.CacheConfig{
+		LaunchpadsTTL:       cfg.SpaceXLaunchpadsCacheTTL,
+		UpcomingLaunchesTTL: cfg.SpaceXUpcomingLaunchesCacheTTL,
+		FetchTimeout:        cfg.SpaceXFetchTimeout,
+	}, l)
+	storage := db.NewPGStorage(pgpool)
+	scheduler, err := schedule.New(cfg.SchedulerStrategy, storage)
+	if err != nil {
+		l.Fatal(err)
+	}
+
+	handlers := api.NewAPI(spacexClient, storage, scheduler, l, api.Config{
+		BookingsTimeout:          cfg.BookingsTimeout,
+		BookingTimeout:           cfg.BookingTimeout,
+		BookFlightTimeout:        cfg.BookFlightTimeout,
+		BookingDeleteTimeout:     cfg.BookingDeleteTimeout,
+		BookingRescheduleTimeout: cfg.BookingRescheduleTimeout,
+		DestinationsTimeout:      cfg.DestinationsTimeout,
+		LaunchpadsTimeout:        cfg.LaunchpadsTimeout,
+		ScheduleTimeout:          cfg.ScheduleTimeout,
+		AvailabilityTimeout:      cfg.AvailabilityTimeout,
+		AdminTimeout:             cfg.AdminTimeout,
+		WaitlistTimeout:          cfg.WaitlistTimeout,
+		DefaultLaunchCapacity:    cfg.DefaultLaunchCapacity,
+		MaxBodyBytes:             cfg.MaxBodyBytes,
+		IdempotencyKeyTTL:        cfg.IdempotencyKeyTTL,
+	})
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go handlers.PurgeIdempotencyKeys(purgeCtx)
+
 	r := chi.NewRouter()
+	r.NotFound(handlers.NotFound)
+	r.MethodNotAllowed(handlers.MethodNotAllowed)
 	r.Get("/booking", handlers.Bookings)
 	r.Post("/booking", handlers.BookFlight)
+	r.Post("/booking/group", handlers.BookGroup)
+	r.Get("/booking/{id}", handlers.Booking)
 	r.Delete("/booking/{id}", handlers.BookingDelete)
+	r.Post("/booking/{id}/reschedule", handlers.RescheduleBooking)
+	r.Get("/booking/{id}/changes", handlers.BookingChanges)
+	r.Post("/waitlist", handlers.JoinWaitlist)
+	r.Get("/destinations", handlers.Destinations)
+	r.Post("/destinations", handlers.CreateDestination)
+	r.Patch("/destinations/{id}", handlers.UpdateDestination)
+	r.Delete("/destinations/{id}", handlers.DeleteDestination)
+	r.Get("/launchpads", handlers.Launchpads)
+	r.Get("/schedule", handlers.Schedule)
+	r.Get("/availability", handlers.Availability)
+	r.Get("/admin/timetable", handlers.Timetable)
+	r.Put("/admin/timetable/{date}/{launchpad_id}", handlers.SetTimetableEntry)
+	r.Delete("/admin/timetable/{date}/{launchpad_id}", handlers.DeleteTimetableEntry)
+	r.Get("/admin/schedule-overrides", handlers.ScheduleOverrides)
+	r.Put("/admin/schedule-overrides/{date}/{launchpad_id}", handlers.SetScheduleOverride)
+	r.Delete("/admin/schedule-overrides/{date}/{launchpad_id}", handlers.DeleteScheduleOverride)
+	r.Get("/admin/launchpad-capacities", handlers.LaunchpadCapacities)
+	r.Put("/admin/launchpad-capacities/{launchpad_id}", handlers.SetLaunchpadCapacity)
+	r.Delete("/admin/launchpad-capacities/{launchpad_id}", handlers.DeleteLaunchpadCapacity)
 
+	// the requests' contexts derive from baseCtx, so the requests still running when the shutdown grace period
+	// ends are cancelled rather than cut off in the middle of their transactions
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 	srv := http.Server{
-		Addr:    ":8080",
-		Handler: r,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+		Addr:              cfg.ListenAddr,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
 
 	go func() {
-		l.Info("Listening on :8080")
+		l.Infof("Listening on %s", cfg.ListenAddr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			l.Fatal("shutting down the server")
 		}
@@ -86,10 +172,23 @@ func main() {
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 	<-quit
 	l.Info("shutting down the server, waiting for in-flight connections to finish")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	if err = srv.Shutdown(ctx); err != nil {
+	if err = shutdown(&srv, cancelRequests, cfg.ShutdownGrace); err != nil {
 		l.Fatal(err)
 	}
 	l.Info("server shut down")
 }
+
+// cancelledRequestsDrain is how long the requests cancelled at the end of the shutdown grace period
+// get to roll back and answer.
+const cancelledRequestsDrain = time.Second
+
+// shutdown stops srv, giving the in-flight requests the grace period to finish. The ones still running
+// after it are cancelled with cancelRequests and get cancelledRequestsDrain to return.
+func shutdown(srv *http.Server, cancelRequests context.CancelFunc, grace time.Duration) error {
+	timer := time.AfterFunc(grace, cancelRequests)
+	defer timer.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace+cancelledRequestsDrain)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
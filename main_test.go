@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	testCases := []struct {
+		name string
+		// work is how long the request runs unless its context is done first
+		work        time.Duration
+		expectedErr error
+	}{
+		{
+			name:        "request finishing within the grace period isn't cancelled",
+			work:        5 * time.Millisecond,
+			expectedErr: nil,
+		},
+		{
+			name:        "request still running when the grace period ends is cancelled",
+			work:        time.Minute,
+			expectedErr: context.Canceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		started := make(chan struct{})
+		ctxErr := make(chan error, 1)
+		baseCtx, cancelRequests := context.WithCancel(context.Background())
+		srv := &http.Server{
+			BaseContext: func(net.Listener) context.Context { return baseCtx },
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(tc.work):
+				case <-r.Context().Done():
+				}
+				ctxErr <- r.Context().Err()
+			}),
+		}
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() { _ = srv.Serve(ln) }()
+		go func() {
+			resp, err := http.Get("http://" + ln.Addr().String())
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+		<-started
+
+		if err = shutdown(srv, cancelRequests, 20*time.Millisecond); err != nil {
+			t.Logf("unexpected shutdown error: %v", err)
+			t.Fail()
+		}
+		if err = <-ctxErr; err != tc.expectedErr {
+			t.Logf("unexpected request context error. Got %v, want %v", err, tc.expectedErr)
+			t.Fail()
+		}
+		cancelRequests()
+	}
+}
@@ -0,0 +1,36 @@
+package schedule
+
+import (
+	"context"
+	"time"
+)
+
+// Rotation shifts the destinations by one launchpad every day. The year day is used to find out which destination
+// is scheduled to which launchpad on a particular day.
+type Rotation struct{}
+
+// Assign goes over the destinations ordered by ID rather than over the IDs themselves,
+// so the rotation stays consistent when destinations are added or deleted.
+func (Rotation) Assign(ctx context.Context, day time.Time, launchpadIDs []string, destinationIDs []int) (map[string]int, error) {
+	launchpadToDestination := make(map[string]int, len(launchpadIDs))
+	if len(destinationIDs) == 0 {
+		return launchpadToDestination, nil
+	}
+
+	for i, id := range launchpadIDs {
+		launchpadToDestination[id] = destinationIDs[(day.YearDay()+i+1)%len(destinationIDs)]
+	}
+	return launchpadToDestination, nil
+}
+
+func (r Rotation) AssignRange(ctx context.Context, from, to time.Time, launchpadIDs []string, destinationIDs []int) ([]map[string]int, error) {
+	var days []map[string]int
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		launchpadToDestination, err := r.Assign(ctx, day, launchpadIDs, destinationIDs)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, launchpadToDestination)
+	}
+	return days, nil
+}
@@ -0,0 +1,34 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	StrategyRotation  = "rotation"
+	StrategyTimetable = "timetable"
+)
+
+// Scheduler decides which destination every launchpad flies to on a day.
+type Scheduler interface {
+	// Assign returns the destination ID by launchpad ID for the day. Launchpads that don't fly on that day are left out.
+	// launchpadIDs and destinationIDs are sorted.
+	Assign(ctx context.Context, day time.Time, launchpadIDs []string, destinationIDs []int) (map[string]int, error)
+	// AssignRange is Assign for every day between from and to inclusive, element i being the day i days after from.
+	// Use it instead of calling Assign day by day, so the schedule of a range is loaded at once.
+	AssignRange(ctx context.Context, from, to time.Time, launchpadIDs []string, destinationIDs []int) ([]map[string]int, error)
+}
+
+// New returns the scheduler for the strategy.
+func New(strategy string, storage TimetableStorage) (Scheduler, error) {
+	switch strategy {
+	case StrategyRotation:
+		return Rotation{}, nil
+	case StrategyTimetable:
+		return NewTimetable(storage), nil
+	default:
+		return nil, fmt.Errorf("unknown scheduling strategy %q", strategy)
+	}
+}
@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"context"
+	"sort"
+	"space-trouble-bookings-api/db"
+	"time"
+)
+
+type TimetableStorage interface {
+	TimetableEntries(ctx context.Context, from, to time.Time) ([]db.TimetableEntry, error)
+}
+
+// Timetable flies only what ops pinned in the timetable table. Launchpads without an entry for the day don't fly.
+type Timetable struct {
+	storage TimetableStorage
+}
+
+func NewTimetable(storage TimetableStorage) *Timetable {
+	return &Timetable{storage: storage}
+}
+
+func (t *Timetable) Assign(ctx context.Context, day time.Time, launchpadIDs []string, destinationIDs []int) (map[string]int, error) {
+	days, err := t.AssignRange(ctx, day, day, launchpadIDs, destinationIDs)
+	if err != nil {
+		return nil, err
+	}
+	return days[0], nil
+}
+
+// AssignRange loads the timetable of the whole range in one query.
+func (t *Timetable) AssignRange(ctx context.Context, from, to time.Time, launchpadIDs []string, destinationIDs []int) ([]map[string]int, error) {
+	entries, err := t.storage.TimetableEntries(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]map[string]int, int(to.Sub(from)/(24*time.Hour))+1)
+	for i := range days {
+		days[i] = make(map[string]int)
+	}
+	for _, entry := range entries {
+		// entries for launchpads SpaceX doesn't have anymore or for deleted destinations are ignored
+		if !containsString(launchpadIDs, entry.LaunchpadID) || !containsInt(destinationIDs, entry.DestinationID) {
+			continue
+		}
+		i := int(entry.LaunchDate.Sub(from) / (24 * time.Hour))
+		if i < 0 || i >= len(days) {
+			continue
+		}
+		days[i][entry.LaunchpadID] = entry.DestinationID
+	}
+	return days, nil
+}
+
+func containsString(sorted []string, value string) bool {
+	i := sort.SearchStrings(sorted, value)
+	return i < len(sorted) && sorted[i] == value
+}
+
+func containsInt(sorted []int, value int) bool {
+	i := sort.SearchInts(sorted, value)
+	return i < len(sorted) && sorted[i] == value
+}
@@ -0,0 +1,96 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"space-trouble-bookings-api/db"
+	"testing"
+	"time"
+)
+
+func TestTimetable_Assign(t *testing.T) {
+	day := time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)
+	timetable := NewTimetable(&timetableStorageMock{entries: []db.TimetableEntry{
+		{LaunchDate: day, LaunchpadID: "a", DestinationID: 2},
+		{LaunchDate: day, LaunchpadID: "b", DestinationID: 9},
+		{LaunchDate: day, LaunchpadID: "removed", DestinationID: 1},
+	}})
+
+	assigned, err := timetable.Assign(context.Background(), day, []string{"a", "b", "c"}, []int{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int{"a": 2}
+	if fmt.Sprint(assigned) != fmt.Sprint(expected) {
+		t.Errorf("unexpected assignment. Got %v, want %v", assigned, expected)
+	}
+}
+
+func TestTimetable_AssignRange(t *testing.T) {
+	from := time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)
+	storage := &timetableStorageMock{entries: []db.TimetableEntry{
+		{LaunchDate: from, LaunchpadID: "a", DestinationID: 2},
+		{LaunchDate: from.AddDate(0, 0, 2), LaunchpadID: "a", DestinationID: 1},
+		{LaunchDate: from.AddDate(0, 0, 2), LaunchpadID: "b", DestinationID: 3},
+		{LaunchDate: from.AddDate(0, 0, 3), LaunchpadID: "a", DestinationID: 3},
+	}}
+	timetable := NewTimetable(storage)
+
+	days, err := timetable.AssignRange(context.Background(), from, from.AddDate(0, 0, 2), []string{"a", "b"}, []int{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]int{{"a": 2}, {}, {"a": 1, "b": 3}}
+	if fmt.Sprint(days) != fmt.Sprint(expected) {
+		t.Errorf("unexpected assignment. Got %v, want %v", days, expected)
+	}
+	if storage.calls != 1 {
+		t.Errorf("unexpected number of queries. Got %d, want 1", storage.calls)
+	}
+}
+
+func TestRotation_AssignRange(t *testing.T) {
+	from := time.Date(2022, 10, 8, 0, 0, 0, 0, time.UTC)
+	launchpadIDs, destinationIDs := []string{"a", "b"}, []int{1, 2, 3}
+
+	days, err := Rotation{}.AssignRange(context.Background(), from, from.AddDate(0, 0, 3), launchpadIDs, destinationIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(days) != 4 {
+		t.Fatalf("unexpected number of days. Got %d, want 4", len(days))
+	}
+	for i, assigned := range days {
+		expected, _ := Rotation{}.Assign(context.Background(), from.AddDate(0, 0, i), launchpadIDs, destinationIDs)
+		if fmt.Sprint(assigned) != fmt.Sprint(expected) {
+			t.Errorf("unexpected assignment of day %d. Got %v, want %v", i, assigned, expected)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("random", nil); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+	if s, err := New(StrategyRotation, nil); err != nil || s != (Rotation{}) {
+		t.Errorf("unexpected scheduler. Got %v, %v", s, err)
+	}
+}
+
+type timetableStorageMock struct {
+	entries []db.TimetableEntry
+	calls   int
+}
+
+func (m *timetableStorageMock) TimetableEntries(ctx context.Context, from, to time.Time) ([]db.TimetableEntry, error) {
+	m.calls++
+	var entries []db.TimetableEntry
+	for _, entry := range m.entries {
+		if !entry.LaunchDate.Before(from) && !entry.LaunchDate.After(to) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,78 @@
+package spacex
+
+import (
+	"sync"
+	"time"
+)
+
+type BreakerConfig struct {
+	// FailureThreshold is the number of failed requests in a row that opens the breaker, 0 disables it
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before a probe request is let through
+	OpenTimeout time.Duration
+}
+
+// circuitBreaker fails requests fast while SpaceX keeps failing. After OpenTimeout a single probe request
+// is let through, and its result decides whether the breaker closes or stays open.
+type circuitBreaker struct {
+	cfg BreakerConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(cfg BreakerConfig) *circuitBreaker {
+	return &circuitBreaker{cfg: cfg, now: time.Now}
+}
+
+// allow reports whether a request can be sent. If not, it also returns when the breaker lets the next one through.
+func (b *circuitBreaker) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.cfg.FailureThreshold == 0 || b.openedAt.IsZero() {
+		return true, 0
+	}
+
+	openFor := b.now().Sub(b.openedAt)
+	if openFor < b.cfg.OpenTimeout {
+		return false, b.cfg.OpenTimeout - openFor
+	}
+	if b.probing {
+		return false, b.cfg.OpenTimeout
+	}
+
+	b.probing = true
+	return true, 0
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openedAt = time.Time{}
+	b.probing = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.probing || b.failures >= b.cfg.FailureThreshold {
+		b.openedAt = b.now()
+		b.probing = false
+	}
+}
+
+// release lets another probe request through when the probe was abandoned without an answer from SpaceX.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
@@ -0,0 +1,127 @@
+package spacex
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type CacheConfig struct {
+	LaunchpadsTTL       time.Duration
+	UpcomingLaunchesTTL time.Duration
+	// FetchTimeout limits a fetch shared by the callers, as it doesn't stop when one of them gives up. 0 means no limit
+	FetchTimeout time.Duration
+}
+
+// NewCachingClient wraps the client with a cache. Concurrent fetches of the same endpoint are collapsed into one
+// request, and when SpaceX fails the last successfully fetched data is served instead.
+func NewCachingClient(c Client, cfg CacheConfig, l *zap.SugaredLogger) Client {
+	return &cachingClient{
+		client: c,
+		log:    l,
+		launchpads: &cachedValue[[]Launchpad]{
+			name:         "launchpads",
+			ttl:          cfg.LaunchpadsTTL,
+			fetchTimeout: cfg.FetchTimeout,
+			now:          time.Now,
+		},
+		upcomingLaunches: &cachedValue[[]Launch]{
+			name:         "upcoming launches",
+			ttl:          cfg.UpcomingLaunchesTTL,
+			fetchTimeout: cfg.FetchTimeout,
+			now:          time.Now,
+		},
+	}
+}
+
+type cachingClient struct {
+	client           Client
+	log              *zap.SugaredLogger
+	launchpads       *cachedValue[[]Launchpad]
+	upcomingLaunches *cachedValue[[]Launch]
+}
+
+func (c *cachingClient) GetAllLaunchpads(ctx context.Context) ([]Launchpad, error) {
+	return c.launchpads.get(ctx, c.log, c.client.GetAllLaunchpads)
+}
+
+func (c *cachingClient) GetUpcomingLaunches(ctx context.Context) ([]Launch, error) {
+	return c.upcomingLaunches.get(ctx, c.log, c.client.GetUpcomingLaunches)
+}
+
+type cachedValue[T any] struct {
+	name         string
+	ttl          time.Duration
+	fetchTimeout time.Duration
+	now          func() time.Time
+
+	mu        sync.Mutex
+	value     T
+	fetchedAt time.Time
+	inflight  *fetchCall[T]
+}
+
+type fetchCall[T any] struct {
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// get returns the cached value, fetching it when it's too old. The fetch is shared by all the callers
+// asking meanwhile, so it runs apart from their contexts: a caller giving up only stops its own waiting.
+func (c *cachedValue[T]) get(ctx context.Context, l *zap.SugaredLogger, fetch func(ctx context.Context) (T, error)) (T, error) {
+	c.mu.Lock()
+	if !c.fetchedAt.IsZero() && c.now().Sub(c.fetchedAt) < c.ttl {
+		value, age := c.value, c.now().Sub(c.fetchedAt)
+		c.mu.Unlock()
+		l.Debugw("serving cached SpaceX data", "endpoint", c.name, "age", age)
+		return value, nil
+	}
+
+	// somebody may be fetching the data already, then wait for the result instead of sending another request
+	call := c.inflight
+	if call == nil {
+		call = &fetchCall[T]{done: make(chan struct{})}
+		c.inflight = call
+		go c.fetch(call, l, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		// the fetch goes on for the other callers, this one just ran out of time waiting for SpaceX
+		var zero T
+		return zero, &UnavailableError{Err: ctx.Err()}
+	}
+}
+
+func (c *cachedValue[T]) fetch(call *fetchCall[T], l *zap.SugaredLogger, fetch func(ctx context.Context) (T, error)) {
+	ctx := context.Background()
+	if c.fetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.fetchTimeout)
+		defer cancel()
+	}
+
+	value, err := fetch(ctx)
+
+	c.mu.Lock()
+	c.inflight = nil
+	switch {
+	case err == nil:
+		c.value, c.fetchedAt = value, c.now()
+		call.value = value
+	case !c.fetchedAt.IsZero():
+		l.Warnw("SpaceX request failed, serving stale data", "endpoint", c.name,
+			"age", c.now().Sub(c.fetchedAt), "error", err)
+		call.value = c.value
+	default:
+		call.err = err
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
@@ -0,0 +1,120 @@
+package spacex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestCachingClient(t *testing.T) {
+	now := time.Date(2022, 8, 31, 12, 0, 0, 0, time.UTC)
+	upstream := &clientMock{launchpads: []Launchpad{{ID: "pad"}}}
+	c := NewCachingClient(upstream, CacheConfig{LaunchpadsTTL: time.Minute}, zap.NewNop().Sugar()).(*cachingClient)
+	c.launchpads.now = func() time.Time { return now }
+
+	t.Log("first call goes to the upstream")
+	launchpads, err := c.GetAllLaunchpads(context.Background())
+	if err != nil || len(launchpads) != 1 || upstream.calls.Load() != 1 {
+		t.Fatalf("unexpected result. Got %v, %v after %d calls", launchpads, err, upstream.calls.Load())
+	}
+
+	t.Log("fresh data is served from the cache")
+	now = now.Add(30 * time.Second)
+	if _, err = c.GetAllLaunchpads(context.Background()); err != nil || upstream.calls.Load() != 1 {
+		t.Fatalf("unexpected result. Got %v after %d calls", err, upstream.calls.Load())
+	}
+
+	t.Log("stale data is served when the upstream fails")
+	now = now.Add(time.Minute)
+	upstream.err = errors.New("spacex is down")
+	launchpads, err = c.GetAllLaunchpads(context.Background())
+	if err != nil || len(launchpads) != 1 || upstream.calls.Load() != 2 {
+		t.Fatalf("unexpected result. Got %v, %v after %d calls", launchpads, err, upstream.calls.Load())
+	}
+
+	t.Log("the error is returned when there is no data to fall back to")
+	if _, err = c.GetUpcomingLaunches(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestCachingClient_CollapsesConcurrentFetches(t *testing.T) {
+	upstream := &clientMock{delay: 20 * time.Millisecond}
+	c := NewCachingClient(upstream, CacheConfig{UpcomingLaunchesTTL: time.Minute}, zap.NewNop().Sugar())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetUpcomingLaunches(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if upstream.calls.Load() != 1 {
+		t.Errorf("unexpected number of upstream calls. Got %d, want 1", upstream.calls.Load())
+	}
+}
+
+func TestCachingClient_CallerGivingUpDoesntCancelFetch(t *testing.T) {
+	upstream := &clientMock{launchpads: []Launchpad{{ID: "pad"}}, delay: 20 * time.Millisecond}
+	c := NewCachingClient(upstream, CacheConfig{LaunchpadsTTL: time.Minute}, zap.NewNop().Sugar())
+
+	// the first caller starts the fetch and disconnects before it's done
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	firstErr := make(chan error)
+	go func() {
+		_, err := c.GetAllLaunchpads(ctx)
+		firstErr <- err
+	}()
+
+	// the second caller joins the same fetch
+	time.Sleep(time.Millisecond)
+	launchpads, err := c.GetAllLaunchpads(context.Background())
+	if err != nil || len(launchpads) != 1 {
+		t.Fatalf("unexpected result. Got %v, %v", launchpads, err)
+	}
+	// the caller answers like SpaceX being unavailable rather than with a bare context error
+	var unavailable *UnavailableError
+	if err = <-firstErr; !errors.As(err, &unavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error of the first caller. Got %v, want an UnavailableError wrapping %v", err, context.DeadlineExceeded)
+	}
+	if upstream.calls.Load() != 1 {
+		t.Errorf("unexpected number of upstream calls. Got %d, want 1", upstream.calls.Load())
+	}
+}
+
+type clientMock struct {
+	launchpads       []Launchpad
+	upcomingLaunches []Launch
+	err              error
+	delay            time.Duration
+	calls            atomic.Int32
+}
+
+func (c *clientMock) GetAllLaunchpads(ctx context.Context) ([]Launchpad, error) {
+	c.calls.Add(1)
+	time.Sleep(c.delay)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.launchpads, c.err
+}
+
+func (c *clientMock) GetUpcomingLaunches(ctx context.Context) ([]Launch, error) {
+	c.calls.Add(1)
+	time.Sleep(c.delay)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.upcomingLaunches, c.err
+}
@@ -2,10 +2,6 @@ package spacex
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
 )
 
 type Launch struct {
@@ -23,28 +19,8 @@ type Launch struct {
 }
 
 func (c *client) GetUpcomingLaunches(ctx context.Context) ([]Launch, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, APIBaseURL+"v5/launches/upcoming", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response code no OK: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
 	var launches []Launch
-	err = json.Unmarshal(body, &launches)
+	err := c.getJSON(ctx, "v5/launches/upcoming", &launches)
 	if err != nil {
 		return nil, err
 	}
@@ -2,10 +2,6 @@ package spacex
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
 )
 
 type Launchpad struct {
@@ -27,28 +23,8 @@ type Launchpad struct {
 }
 
 func (c *client) GetAllLaunchpads(ctx context.Context) ([]Launchpad, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, APIBaseURL+"v4/launchpads", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := c.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("response code no OK: %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
 	var launchpads []Launchpad
-	err = json.Unmarshal(body, &launchpads)
+	err := c.getJSON(ctx, "v4/launchpads", &launchpads)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,142 @@
+package spacex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrCircuitOpen is wrapped by UnavailableError when the request wasn't sent because of the circuit breaker.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// UnavailableError is returned when the SpaceX API can't be reached or keeps answering with server errors.
+// RetryAfter is a hint on when it makes sense to try again.
+type UnavailableError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("SpaceX API is unavailable: %s", e.Err.Error())
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+type RetryConfig struct {
+	// MaxAttempts is the number of requests sent before giving up, including the first one
+	MaxAttempts int
+	BaseDelay   time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than that isn't waited for, the error is returned instead
+	MaxDelay time.Duration
+}
+
+// getJSON fetches the path and decodes the response into v. Transport errors, timeouts, 429 and 5xx responses
+// are retried with jittered exponential backoff.
+func (c *client) getJSON(ctx context.Context, path string, v interface{}) error {
+	for attempt := 1; ; attempt++ {
+		if ok, retryAfter := c.breaker.allow(); !ok {
+			return &UnavailableError{RetryAfter: retryAfter, Err: ErrCircuitOpen}
+		}
+
+		// every outcome settles the breaker, otherwise a probe request would keep it open for good
+		retryAfter, retryable, err := c.get(ctx, path, v)
+		switch {
+		case err == nil:
+			c.breaker.success()
+			return nil
+		case ctx.Err() != nil:
+			// the caller gave up, which tells nothing about SpaceX
+			c.breaker.release()
+			return err
+		case !retryable:
+			// SpaceX answered, it's the request or the response that's wrong
+			c.breaker.success()
+			return err
+		}
+		c.breaker.failure()
+
+		delay := c.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if attempt >= c.retry.MaxAttempts || delay > c.retry.MaxDelay {
+			return &UnavailableError{RetryAfter: delay, Err: err}
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return &UnavailableError{RetryAfter: delay, Err: err}
+		}
+	}
+}
+
+// get sends a single request. It reports whether the failure is worth retrying
+// and how long the server asked to wait before that.
+func (c *client) get(ctx context.Context, path string, v interface{}) (time.Duration, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return 0, false, err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		// the caller gave up, there is no point in retrying
+		if ctx.Err() != nil {
+			return 0, false, err
+		}
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("response code no OK: %d", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+			return parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()), true, err
+		}
+		return 0, false, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, true, err
+	}
+
+	return 0, false, json.Unmarshal(body, v)
+}
+
+// backoff returns a random delay between 0 and BaseDelay*2^(attempt-1), capped by MaxDelay.
+func (c *client) backoff(attempt int) time.Duration {
+	delay := c.retry.MaxDelay
+	if attempt < 32 && c.retry.BaseDelay<<(attempt-1) < delay {
+		delay = c.retry.BaseDelay << (attempt - 1)
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(delay)))
+}
+
+// parseRetryAfter supports both forms of the Retry-After header, delay in seconds and HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,192 @@
+package spacex
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Retry(t *testing.T) {
+	testCases := []struct {
+		name            string
+		responses       []int
+		retryAfter      string
+		expectedCalls   int32
+		expectedErr     bool
+		unavailableErr  bool
+		expectedRetryIn time.Duration
+	}{
+		{
+			name:          "server errors are retried",
+			responses:     []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			expectedCalls: 3,
+		},
+		{
+			name:           "gives up after max attempts",
+			responses:      []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			expectedCalls:  3,
+			expectedErr:    true,
+			unavailableErr: true,
+		},
+		{
+			name:          "client errors are not retried",
+			responses:     []int{http.StatusNotFound},
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+		{
+			name:            "too long Retry-After is not waited for",
+			responses:       []int{http.StatusTooManyRequests},
+			retryAfter:      "120",
+			expectedCalls:   1,
+			expectedErr:     true,
+			unavailableErr:  true,
+			expectedRetryIn: 120 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Log(tc.name)
+
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := tc.responses[calls.Add(1)-1]
+			if tc.retryAfter != "" {
+				w.Header().Set("Retry-After", tc.retryAfter)
+			}
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`[]`))
+		}))
+
+		c := NewClient(srv.Client(), WithBaseURL(srv.URL),
+			WithRetry(RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}),
+			WithCircuitBreaker(BreakerConfig{}),
+		)
+		_, err := c.GetAllLaunchpads(context.Background())
+		srv.Close()
+
+		if calls.Load() != tc.expectedCalls {
+			t.Errorf("unexpected number of calls. Got %d, want %d", calls.Load(), tc.expectedCalls)
+		}
+		if (err != nil) != tc.expectedErr {
+			t.Errorf("unexpected error: %v", err)
+		}
+		var unavailable *UnavailableError
+		if errors.As(err, &unavailable) != tc.unavailableErr {
+			t.Errorf("unexpected error type: %v", err)
+		}
+		if tc.expectedRetryIn != 0 && unavailable.RetryAfter != tc.expectedRetryIn {
+			t.Errorf("unexpected retry after. Got %s, want %s", unavailable.RetryAfter, tc.expectedRetryIn)
+		}
+	}
+}
+
+func TestClient_CircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	now := time.Date(2022, 8, 31, 12, 0, 0, 0, time.UTC)
+	c := NewClient(srv.Client(), WithBaseURL(srv.URL),
+		WithRetry(RetryConfig{MaxAttempts: 1}),
+		WithCircuitBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}),
+	).(*client)
+	c.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetUpcomingLaunches(context.Background()); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+
+	t.Log("open breaker fails fast")
+	_, err := c.GetUpcomingLaunches(context.Background())
+	if !errors.Is(err, ErrCircuitOpen) || calls.Load() != 2 {
+		t.Fatalf("unexpected result. Got %v after %d calls", err, calls.Load())
+	}
+	var unavailable *UnavailableError
+	if !errors.As(err, &unavailable) || unavailable.RetryAfter != time.Minute {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Log("probe request closes the breaker")
+	now = now.Add(time.Minute)
+	healthy.Store(true)
+	if _, err = c.GetUpcomingLaunches(context.Background()); err != nil || calls.Load() != 3 {
+		t.Fatalf("unexpected result. Got %v after %d calls", err, calls.Load())
+	}
+	if _, err = c.GetUpcomingLaunches(context.Background()); err != nil || calls.Load() != 4 {
+		t.Fatalf("unexpected result. Got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestClient_CircuitBreaker_ProbeOutcomes(t *testing.T) {
+	var calls atomic.Int32
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	now := time.Date(2022, 8, 31, 12, 0, 0, 0, time.UTC)
+	c := NewClient(srv.Client(), WithBaseURL(srv.URL),
+		WithRetry(RetryConfig{MaxAttempts: 1}),
+		WithCircuitBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}),
+	).(*client)
+	c.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetUpcomingLaunches(context.Background()); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	now = now.Add(time.Minute)
+
+	t.Log("cancelled probe lets the next one through")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetUpcomingLaunches(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status.Store(http.StatusNotFound)
+	_, err := c.GetUpcomingLaunches(context.Background())
+	if err == nil || errors.Is(err, ErrCircuitOpen) || calls.Load() != 3 {
+		t.Fatalf("unexpected result. Got %v after %d calls", err, calls.Load())
+	}
+
+	t.Log("probe answered with a client error closes the breaker")
+	status.Store(http.StatusOK)
+	if _, err = c.GetUpcomingLaunches(context.Background()); err != nil || calls.Load() != 4 {
+		t.Fatalf("unexpected result. Got %v after %d calls", err, calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 8, 31, 12, 0, 0, 0, time.UTC)
+	testCases := map[string]time.Duration{
+		"":                              0,
+		"30":                            30 * time.Second,
+		"invalid":                       0,
+		"Wed, 31 Aug 2022 12:01:00 GMT": time.Minute,
+	}
+	for value, expected := range testCases {
+		if got := parseRetryAfter(value, now); got != expected {
+			t.Errorf("unexpected delay for %q. Got %s, want %s", value, got, expected)
+		}
+	}
+}
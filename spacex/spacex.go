@@ -3,10 +3,12 @@ package spacex
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 )
 
 const (
+	// APIBaseURL is the public SpaceX API used unless WithBaseURL is passed to NewClient
 	APIBaseURL = "https://api.spacexdata.com/"
 )
 
@@ -20,18 +22,59 @@ type Images struct {
 	Large []string `json:"large,omitempty"`
 }
 
-func NewClient(httpClient *http.Client) Client {
+type Option func(c *client)
+
+// WithBaseURL points the client to another SpaceX API compatible server, e.g. a local stub.
+func WithBaseURL(baseURL string) Option {
+	return func(c *client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	}
+}
+
+// WithRetry overrides the default retry policy.
+func WithRetry(cfg RetryConfig) Option {
+	return func(c *client) {
+		c.retry = cfg
+	}
+}
+
+// WithCircuitBreaker overrides the default circuit breaker settings.
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(c *client) {
+		c.breaker = newCircuitBreaker(cfg)
+	}
+}
+
+func NewClient(httpClient *http.Client, opts ...Option) Client {
 	if httpClient == nil {
 		httpClient = &http.Client{
 			Timeout: time.Second * 10,
 		}
 	}
 
-	return &client{
-		httpClient,
+	c := &client{
+		Client:  httpClient,
+		baseURL: APIBaseURL,
+		retry: RetryConfig{
+			MaxAttempts: 3,
+			BaseDelay:   200 * time.Millisecond,
+			MaxDelay:    2 * time.Second,
+		},
+		breaker: newCircuitBreaker(BreakerConfig{
+			FailureThreshold: 5,
+			OpenTimeout:      30 * time.Second,
+		}),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+
+	return c
 }
 
 type client struct {
 	*http.Client
+	baseURL string
+	retry   RetryConfig
+	breaker *circuitBreaker
 }
@@ -0,0 +1,34 @@
+package spacex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_WithBaseURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/launchpads":
+			_, _ = w.Write([]byte(`[{"id":"pad","status":"active"}]`))
+		case "/v5/launches/upcoming":
+			_, _ = w.Write([]byte(`[{"launchpad":"pad","date_utc":"2030-01-15T05:40:00.000Z"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.Client(), WithBaseURL(srv.URL))
+
+	launchpads, err := c.GetAllLaunchpads(context.Background())
+	if err != nil || len(launchpads) != 1 || launchpads[0].ID != "pad" {
+		t.Errorf("unexpected launchpads. Got %v, %v", launchpads, err)
+	}
+
+	launches, err := c.GetUpcomingLaunches(context.Background())
+	if err != nil || len(launches) != 1 || launches[0].Launchpad != "pad" {
+		t.Errorf("unexpected launches. Got %v, %v", launches, err)
+	}
+}